@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AppStream is a PulseAudio sink input, i.e. one application's playback.
+type AppStream struct {
+	Index  string
+	Name   string
+	PID    string
+	Binary string
+	Media  string
+	Spec   string
+	Corked bool
+}
+
+func (as AppStream) Label() string {
+	label := as.Name
+	if label == "" {
+		label = as.Binary
+	}
+	if as.Media != "" && as.Media != label {
+		label += " — " + as.Media
+	}
+	if as.PID != "" {
+		label += " (pid " + as.PID + ")"
+	}
+	return label
+}
+
+func ListAppStreams() ([]AppStream, error) {
+	out, err := exec.Command("pactl", "list", "sink-inputs").Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list applications: %w", err)
+	}
+	return parseSinkInputs(string(out)), nil
+}
+
+func parseSinkInputs(out string) []AppStream {
+	var streams []AppStream
+	var cur *AppStream
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if idx, ok := strings.CutPrefix(line, "Sink Input #"); ok {
+			streams = append(streams, AppStream{Index: idx})
+			cur = &streams[len(streams)-1]
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if v, ok := strings.CutPrefix(line, "Sample Specification:"); ok {
+			cur.Spec = strings.TrimSpace(v)
+			continue
+		}
+		if v, ok := strings.CutPrefix(line, "Corked:"); ok {
+			cur.Corked = strings.TrimSpace(v) == "yes"
+			continue
+		}
+		key, value, ok := strings.Cut(line, " = ")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		switch key {
+		case "application.name":
+			cur.Name = value
+		case "application.process.id":
+			cur.PID = value
+		case "application.process.binary":
+			cur.Binary = value
+		case "media.name":
+			cur.Media = value
+		}
+	}
+	return streams
+}
+
+// matchApp picks the stream for app, which is a PID or an application name
+// or binary. Exact names win over substrings and playing streams over
+// paused ones.
+func matchApp(streams []AppStream, app string) (AppStream, bool) {
+	best, bestScore := AppStream{}, 0
+	_, err := strconv.Atoi(app)
+	isPID := err == nil
+	for _, s := range streams {
+		score := 0
+		switch {
+		case isPID:
+			if s.PID == app {
+				score = 2
+			}
+		case strings.EqualFold(s.Name, app) || strings.EqualFold(s.Binary, app):
+			score = 2
+		case containsFold(s.Name, app) || containsFold(s.Binary, app):
+			score = 1
+		}
+		if score == 0 {
+			continue
+		}
+		score *= 2
+		if !s.Corked {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = s, score
+		}
+	}
+	return best, bestScore > 0
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// appBackend records a single application's sink input with
+// parec --monitor-stream. The stream index is looked up again on every
+// (re)start since players often recreate their stream between tracks.
+// sinkInput pins one stream picked from a list for as long as it exists.
+type appBackend struct {
+	app       string
+	sinkInput string
+}
+
+func (appBackend) Name() string { return "parec" }
+
+func (appBackend) Available() bool { return commandAvailable("parec", "pactl") }
+
+func (ab appBackend) DefaultDevice() (string, error) {
+	streams, err := ListAppStreams()
+	if err != nil {
+		return "", err
+	}
+	for _, s := range streams {
+		if ab.sinkInput != "" && s.Index == ab.sinkInput {
+			return s.Index, nil
+		}
+	}
+	s, ok := matchApp(streams, ab.app)
+	if !ok {
+		return "", fmt.Errorf("%s is not playing", ab.app)
+	}
+	return s.Index, nil
+}
+
+func (appBackend) Command(device string, sampleRate, channels int, latency time.Duration) *exec.Cmd {
+	return exec.Command("parec",
+		"--format=float32le",
+		fmt.Sprintf("--rate=%d", sampleRate),
+		fmt.Sprintf("--channels=%d", channels),
+		fmt.Sprintf("--monitor-stream=%s", device),
+		fmt.Sprintf("--latency-msec=%d", latency.Milliseconds()),
+	)
+}
+
+func (appBackend) NativeSpec(device string) (int, int, error) {
+	streams, err := ListAppStreams()
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, s := range streams {
+		if s.Index == device {
+			return parseSampleSpec(s.Spec)
+		}
+	}
+	return 0, 0, fmt.Errorf("sink input #%s is gone", device)
+}
+
+func (appBackend) WatchDefault(done <-chan struct{}, changed func()) {
+	watchPactl(done, "on sink-input", changed)
+}
+
+// watchPactl runs pactl subscribe and calls changed for every event line
+// containing match, restarting pactl if it exits.
+func watchPactl(done <-chan struct{}, match string, changed func()) {
+	for {
+		cmd := exec.Command("pactl", "subscribe")
+		stdout, err := cmd.StdoutPipe()
+		if err == nil {
+			err = cmd.Start()
+		}
+		if err == nil {
+			exited := make(chan struct{})
+			go func() {
+				select {
+				case <-done:
+					_ = cmd.Process.Kill()
+				case <-exited:
+				}
+			}()
+
+			scanner := bufio.NewScanner(stdout)
+			for scanner.Scan() {
+				if strings.Contains(scanner.Text(), match) {
+					changed()
+				}
+			}
+			_ = cmd.Wait()
+			close(exited)
+		}
+
+		select {
+		case <-done:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sinkInputs = `Sink Input #41
+	Driver: protocol-native.c
+	Owner Module: 9
+	Client: 60
+	Sink: 1
+	Sample Specification: float32le 2ch 48000Hz
+	Channel Map: front-left,front-right
+	Format: pcm, format.sample_format = "\"float32le\""  format.rate = "48000"  format.channels = "2"
+	Corked: yes
+	Mute: no
+	Volume: front-left: 65536 / 100% / 0.00 dB,   front-right: 65536 / 100% / 0.00 dB
+	Properties:
+		media.name = "Playback"
+		application.name = "Firefox"
+		application.process.id = "1234"
+		application.process.binary = "firefox"
+Sink Input #57
+	Driver: protocol-native.c
+	Sample Specification: s16le 2ch 44100Hz
+	Corked: no
+	Properties:
+		media.name = "Lo-fi beats"
+		application.name = "Firefox"
+		application.process.id = "1234"
+		application.process.binary = "firefox"
+Sink Input #63
+	Driver: PipeWire
+	Sample Specification: float32le 2ch 48000Hz
+	Corked: no
+	Properties:
+		media.name = "audio stream #1"
+		application.name = "mpv"
+		application.process.id = "5678"
+		application.process.binary = "mpv"
+Sink Input #70
+	Driver: PipeWire
+	Sample Specification: s16le 1ch 22050Hz
+	Corked: no
+	Properties:
+		application.name = "Firefox Developer Edition"
+		application.process.id = "9012"
+		application.process.binary = "firefox-dev"
+`
+
+func TestParseSinkInputs(t *testing.T) {
+	streams := parseSinkInputs(sinkInputs)
+	if len(streams) != 4 {
+		t.Fatalf("parsed %d streams, want 4", len(streams))
+	}
+	want := AppStream{Index: "41", Name: "Firefox", PID: "1234", Binary: "firefox", Media: "Playback", Spec: "float32le 2ch 48000Hz", Corked: true}
+	if streams[0] != want {
+		t.Errorf("first stream\n got %+v\nwant %+v", streams[0], want)
+	}
+	if streams[1].Corked || streams[1].Media != "Lo-fi beats" {
+		t.Errorf("second stream %+v", streams[1])
+	}
+	if got := streams[2].Label(); got != "mpv — audio stream #1 (pid 5678)" {
+		t.Errorf("label %q", got)
+	}
+}
+
+func TestMatchApp(t *testing.T) {
+	streams := parseSinkInputs(sinkInputs)
+	tests := []struct {
+		app  string
+		want string
+		ok   bool
+	}{
+		{"5678", "63", true}, // pid
+		{"9012", "70", true},
+		{"mpv", "63", true},     // name
+		{"MPV", "63", true},     // names ignore case
+		{"firefox", "57", true}, // exact name beats "Firefox Developer Edition", playing beats paused
+		{"developer", "70", true},
+		{"firefox-dev", "70", true}, // binary
+		{"fox", "57", true},         // substring, playing first
+		{"vlc", "", false},
+		{"4321", "", false}, // an unknown pid is not a substring search
+	}
+	for _, tt := range tests {
+		s, ok := matchApp(streams, tt.app)
+		if ok != tt.ok || s.Index != tt.want {
+			t.Errorf("matchApp(%q) = #%s, %v; want #%s, %v", tt.app, s.Index, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMatchAppPrefersPlaying(t *testing.T) {
+	streams := []AppStream{
+		{Index: "1", Name: "spotify", Corked: true},
+		{Index: "2", Name: "spotify"},
+	}
+	if s, _ := matchApp(streams, "spotify"); s.Index != "2" {
+		t.Errorf("picked #%s, want the playing #2", s.Index)
+	}
+	streams[1].Corked = true
+	if s, ok := matchApp(streams, "spotify"); !ok || s.Index != "1" {
+		t.Errorf("all paused: picked #%s %v, want the first", s.Index, ok)
+	}
+}
+
+func fakePulse(t *testing.T) string {
+	return fakeCommands(t, map[string]string{
+		"parec": "",
+		"pactl": `case "$*" in
+"list sink-inputs") printf '%s' '` + sinkInputs + `' ;;
+subscribe) exec $SLEEP 10 ;;
+esac
+`,
+	})
+}
+
+func TestAppBackend(t *testing.T) {
+	fakePulse(t)
+
+	ab := appBackend{app: "mpv"}
+	if !ab.Available() {
+		t.Fatal("appBackend not available with parec and pactl on PATH")
+	}
+	if idx, err := ab.DefaultDevice(); err != nil || idx != "63" {
+		t.Errorf("DefaultDevice = %q, %v; want 63", idx, err)
+	}
+	if _, err := (appBackend{app: "vlc"}).DefaultDevice(); err == nil || !strings.Contains(err.Error(), "not playing") {
+		t.Errorf("missing app: %v", err)
+	}
+	if idx, _ := (appBackend{app: "Firefox", sinkInput: "41"}).DefaultDevice(); idx != "41" {
+		t.Errorf("pinned to the paused #41, got #%s", idx)
+	}
+	if idx, _ := (appBackend{app: "Firefox", sinkInput: "99"}).DefaultDevice(); idx != "57" {
+		t.Errorf("pinned stream gone: got #%s, want firefox's playing #57", idx)
+	}
+	if rate, ch, err := ab.NativeSpec("70"); rate != 22050 || ch != 1 || err != nil {
+		t.Errorf("NativeSpec(70) = %d, %d, %v", rate, ch, err)
+	}
+
+	cmd := ab.Command("63", 48000, 2, 20*time.Millisecond)
+	want := "parec --format=float32le --rate=48000 --channels=2 --monitor-stream=63 --latency-msec=20"
+	if got := strings.Join(cmd.Args, " "); got != want {
+		t.Errorf("argv\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestOpenCaptureApp(t *testing.T) {
+	log := fakePulse(t)
+
+	cfg := DefaultConfig()
+	cfg.Audio.App = "firefox"
+	cfg.applyAudioDefaults()
+	c, err := OpenCapture(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Device() != "57" {
+		t.Errorf("capturing #%s, want the playing firefox stream #57", c.Device())
+	}
+	if cfg.Audio.SampleRate != 44100 || cfg.Audio.Channels != 2 {
+		t.Errorf("spec %dHz %dch, want the stream's 44100Hz 2ch", cfg.Audio.SampleRate, cfg.Audio.Channels)
+	}
+	want := "--format=float32le --rate=44100 --channels=2 --monitor-stream=57 --latency-msec=25"
+	if calls := fakeCalls(t, log, "parec"); !slices.Contains(calls, want) {
+		t.Errorf("parec calls %q, want %q", calls, want)
+	}
+}
+
+func TestOpenCapturePickedStream(t *testing.T) {
+	log := fakePulse(t)
+
+	cfg := DefaultConfig()
+	cfg.Audio.App = "Firefox"
+	cfg.Audio.SinkInput = "41"
+	cfg.applyAudioDefaults()
+	c, err := OpenCapture(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Device() != "41" {
+		t.Errorf("capturing #%s, want the picked paused stream #41", c.Device())
+	}
+	want := "--format=float32le --rate=48000 --channels=2 --monitor-stream=41 --latency-msec=25"
+	if calls := fakeCalls(t, log, "parec"); !slices.Contains(calls, want) {
+		t.Errorf("parec calls %q, want %q", calls, want)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+type CaptureBackend interface {
+	Name() string
+	Available() bool
+	DefaultDevice() (string, error)
+	Command(device string, sampleRate, channels int, latency time.Duration) *exec.Cmd
+}
+
+type DefaultWatcher interface {
+	WatchDefault(done <-chan struct{}, changed func())
+}
+
+// SpecProber reports the native sample rate and channel count of a device,
+// so capture can run without the server resampling.
+type SpecProber interface {
+	NativeSpec(device string) (rate, channels int, err error)
+}
+
+var captureBackends = []CaptureBackend{
+	parecBackend{},
+	pwRecordBackend{},
+	arecordBackend{},
+	soxBackend{},
+}
+
+var defaultBackendOrder = []string{"parec", "pw-record", "arecord", "sox"}
+
+const (
+	captureDefaultLatency = 25 * time.Millisecond
+	captureMinLatency     = 5 * time.Millisecond
+)
+
+// captureLatency is the buffering asked of the capture backend. A negative
+// latency offset takes what it can from the default before the visuals
+// would have to run ahead of the audio.
+func captureLatency(offsetMS int) time.Duration {
+	if offsetMS >= 0 {
+		return captureDefaultLatency
+	}
+	return max(captureDefaultLatency+time.Duration(offsetMS)*time.Millisecond, captureMinLatency)
+}
+
+func GetCaptureBackend(name string) (CaptureBackend, error) {
+	for _, b := range captureBackends {
+		if b.Name() == name {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown capture backend %q", name)
+}
+
+func OpenCapture(cfg *Config) (*Capture, error) {
+	if cfg.Audio.App != "" {
+		backend := appBackend{app: cfg.Audio.App, sinkInput: cfg.Audio.SinkInput}
+		if !backend.Available() {
+			return nil, errors.New("capturing an application needs parec and pactl")
+		}
+		return newCaptureFor(cfg, backend, "")
+	}
+
+	order := cfg.Audio.Backends
+	if cfg.Audio.Backend != "" && cfg.Audio.Backend != "auto" {
+		order = []string{cfg.Audio.Backend}
+	}
+	if len(order) == 0 {
+		order = defaultBackendOrder
+	}
+
+	var errs []error
+	for _, name := range order {
+		backend, err := GetCaptureBackend(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !backend.Available() {
+			errs = append(errs, fmt.Errorf("%s: not installed", name))
+			continue
+		}
+		capture, err := newCaptureFor(cfg, backend, cfg.Audio.Device)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		return capture, nil
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no capture backend configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// newCaptureFor starts a capture with the audio settings from cfg. A rate
+// or channel count left on auto is probed from the device, and cfg is
+// updated with what the capture settled on.
+func newCaptureFor(cfg *Config, backend CaptureBackend, device string) (*Capture, error) {
+	rate, channels := cfg.Audio.SampleRate, cfg.Audio.Channels
+	if cfg.Audio.AutoRate {
+		rate = 0
+	}
+	if cfg.Audio.AutoChannels {
+		channels = 0
+	}
+	capture, err := NewCapture(backend, device, captureLatency(cfg.Audio.LatencyOffsetMS), rate, cfg.Audio.BufferSize, cfg.Audio.HopSize, channels)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Audio.SampleRate = capture.SampleRate()
+	cfg.Audio.Channels = capture.Channels()
+	return capture, nil
+}
+
+func commandAvailable(names ...string) bool {
+	for _, name := range names {
+		if _, err := exec.LookPath(name); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+type parecBackend struct{}
+
+func (parecBackend) Name() string { return "parec" }
+
+func (parecBackend) Available() bool { return commandAvailable("parec", "pactl") }
+
+func (parecBackend) DefaultDevice() (string, error) {
+	monitor, err := getMonitorSource()
+	if err != nil {
+		return "", fmt.Errorf("failed to find monitor source: %w", err)
+	}
+	return monitor, nil
+}
+
+func (parecBackend) Command(device string, sampleRate, channels int, latency time.Duration) *exec.Cmd {
+	return exec.Command("parec",
+		"--format=float32le",
+		fmt.Sprintf("--rate=%d", sampleRate),
+		fmt.Sprintf("--channels=%d", channels),
+		fmt.Sprintf("--device=%s", device),
+		fmt.Sprintf("--latency-msec=%d", latency.Milliseconds()),
+	)
+}
+
+func (parecBackend) NativeSpec(device string) (int, int, error) {
+	sources, err := ListSources()
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, s := range sources {
+		if s.Name == device {
+			return parseSampleSpec(s.Spec)
+		}
+	}
+	return 0, 0, fmt.Errorf("unknown source %s", device)
+}
+
+func (parecBackend) WatchDefault(done <-chan struct{}, changed func()) {
+	watchPactl(done, "'change' on server", changed)
+}
+
+func getMonitorSource() (string, error) {
+	out, err := exec.Command("pactl", "get-default-sink").Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot get default sink: %w", err)
+	}
+	sink := strings.TrimSpace(string(out))
+	if sink == "" {
+		return "", fmt.Errorf("no default sink found")
+	}
+	return sink + ".monitor", nil
+}
+
+type pwRecordBackend struct{}
+
+func (pwRecordBackend) Name() string { return "pw-record" }
+
+func (pwRecordBackend) Available() bool { return commandAvailable("pw-record") }
+
+func (pwRecordBackend) DefaultDevice() (string, error) { return "", nil }
+
+func (pwRecordBackend) Command(device string, sampleRate, channels int, latency time.Duration) *exec.Cmd {
+	args := []string{
+		"--raw",
+		"--format=f32",
+		fmt.Sprintf("--rate=%d", sampleRate),
+		fmt.Sprintf("--channels=%d", channels),
+		fmt.Sprintf("--latency=%dms", latency.Milliseconds()),
+	}
+	if device == "" {
+		args = append(args, "-P", "stream.capture.sink=true")
+	} else {
+		args = append(args, fmt.Sprintf("--target=%s", device))
+	}
+	return exec.Command("pw-record", append(args, "-")...)
+}
+
+type arecordBackend struct{}
+
+func (arecordBackend) Name() string { return "arecord" }
+
+func (arecordBackend) Available() bool { return commandAvailable("arecord") }
+
+func (arecordBackend) DefaultDevice() (string, error) { return "default", nil }
+
+func (arecordBackend) Command(device string, sampleRate, channels int, latency time.Duration) *exec.Cmd {
+	return exec.Command("arecord",
+		"-q",
+		"-t", "raw",
+		"-f", "FLOAT_LE",
+		"-r", fmt.Sprint(sampleRate),
+		"-c", fmt.Sprint(channels),
+		"-D", device,
+		fmt.Sprintf("--buffer-time=%d", latency.Microseconds()),
+		"-",
+	)
+}
+
+type soxBackend struct{}
+
+func (soxBackend) Name() string { return "sox" }
+
+func (soxBackend) Available() bool { return commandAvailable("sox") }
+
+func (soxBackend) DefaultDevice() (string, error) { return "", nil }
+
+func (soxBackend) Command(device string, sampleRate, channels int, latency time.Duration) *exec.Cmd {
+	cmd := exec.Command("sox",
+		"-q",
+		"--buffer", fmt.Sprint(int(latency.Seconds()*float64(sampleRate*channels*4))),
+		"-d",
+		"-t", "raw",
+		"-e", "floating-point",
+		"-b", "32",
+		"-L",
+		"-c", fmt.Sprint(channels),
+		"-r", fmt.Sprint(sampleRate),
+		"-",
+	)
+	if device != "" {
+		cmd.Env = append(os.Environ(), "AUDIODEV="+device)
+	}
+	return cmd
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCommands puts shell scripts named after the keys of scripts on an
+// otherwise empty PATH. Every call is appended to the returned log as
+// "name args...". $SLEEP in a script is the real sleep binary, and an empty
+// script just blocks like a capture command would.
+func fakeCommands(t *testing.T, scripts map[string]string) string {
+	t.Helper()
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("no sleep binary")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	for name, body := range scripts {
+		if body == "" {
+			body = "exec $SLEEP 10\n"
+		}
+		script := "#!/bin/sh\nSLEEP=" + sleep + "\necho \"${0##*/} $*\" >> " + log + "\n" + body
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+	return log
+}
+
+// fakeCalls returns the logged calls of the named command, waiting a
+// little for a command that has only just been started to log itself.
+func fakeCalls(t *testing.T, log, name string) []string {
+	t.Helper()
+	var calls []string
+	for deadline := time.Now().Add(2 * time.Second); len(calls) == 0 && time.Now().Before(deadline); {
+		data, err := os.ReadFile(log)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if args, ok := strings.CutPrefix(line, name+" "); ok {
+				calls = append(calls, args)
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return calls
+}
+
+func testCaptureConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.Audio.SampleRate = 44100
+	cfg.Audio.Channels = 2
+	return cfg
+}
+
+func TestOpenCaptureAutoOrder(t *testing.T) {
+	fakeCommands(t, map[string]string{"arecord": "", "sox": ""})
+
+	cfg := testCaptureConfig()
+	c, err := OpenCapture(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if c.Backend() != "arecord" {
+		t.Errorf("auto picked %s, want arecord as the first installed", c.Backend())
+	}
+
+	cfg.Audio.Backends = []string{"sox", "arecord"}
+	c, err = OpenCapture(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if c.Backend() != "sox" {
+		t.Errorf("auto picked %s, want sox from audio.backends", c.Backend())
+	}
+}
+
+func TestOpenCaptureForcedBackend(t *testing.T) {
+	fakeCommands(t, map[string]string{"arecord": "", "sox": ""})
+
+	cfg := testCaptureConfig()
+	cfg.Audio.Backend = "sox"
+	c, err := OpenCapture(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if c.Backend() != "sox" {
+		t.Errorf("forced sox, got %s", c.Backend())
+	}
+
+	cfg.Audio.Backend = "pw-record"
+	if _, err := OpenCapture(cfg); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("forcing a missing backend: got %v, want not installed", err)
+	}
+
+	cfg.Audio.Backend = "nope"
+	if _, err := OpenCapture(cfg); err == nil || !strings.Contains(err.Error(), "unknown capture backend") {
+		t.Errorf("forcing an unknown backend: got %v", err)
+	}
+}
+
+func TestOpenCaptureRunsBackend(t *testing.T) {
+	log := fakeCommands(t, map[string]string{
+		"parec": "",
+		"pactl": `case "$*" in
+"get-default-sink") echo alsa_output.pci ;;
+"list short sources") printf '1\talsa_output.pci.monitor\tmodule-alsa-card.c\tfloat32le 2ch 48000Hz\tIDLE\n' ;;
+subscribe) exec $SLEEP 10 ;;
+esac
+`,
+	})
+
+	cfg := DefaultConfig()
+	cfg.applyAudioDefaults()
+	c, err := OpenCapture(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if cfg.Audio.SampleRate != 48000 || cfg.Audio.Channels != 2 {
+		t.Errorf("auto spec = %dHz %dch, want the monitor's 48000Hz 2ch", cfg.Audio.SampleRate, cfg.Audio.Channels)
+	}
+	want := "--format=float32le --rate=48000 --channels=2 --device=alsa_output.pci.monitor --latency-msec=25"
+	if calls := fakeCalls(t, log, "parec"); !slices.Contains(calls, want) {
+		t.Errorf("parec calls %q, want %q", calls, want)
+	}
+}
+
+func TestBackendCommands(t *testing.T) {
+	latency := 20 * time.Millisecond
+	tests := []struct {
+		backend CaptureBackend
+		device  string
+		want    string
+	}{
+		{parecBackend{}, "out.monitor", "parec --format=float32le --rate=48000 --channels=2 --device=out.monitor --latency-msec=20"},
+		{pwRecordBackend{}, "", "pw-record --raw --format=f32 --rate=48000 --channels=2 --latency=20ms -P stream.capture.sink=true -"},
+		{pwRecordBackend{}, "alsa_input", "pw-record --raw --format=f32 --rate=48000 --channels=2 --latency=20ms --target=alsa_input -"},
+		{arecordBackend{}, "hw:1", "arecord -q -t raw -f FLOAT_LE -r 48000 -c 2 -D hw:1 --buffer-time=20000 -"},
+		{soxBackend{}, "", "sox -q --buffer 7680 -d -t raw -e floating-point -b 32 -L -c 2 -r 48000 -"},
+	}
+	for _, tt := range tests {
+		cmd := tt.backend.Command(tt.device, 48000, 2, latency)
+		if got := strings.Join(cmd.Args, " "); got != tt.want {
+			t.Errorf("%s %q:\n got %s\nwant %s", tt.backend.Name(), tt.device, got, tt.want)
+		}
+	}
+
+	cmd := soxBackend{}.Command("hw:0", 48000, 2, latency)
+	if !slices.Contains(cmd.Env, "AUDIODEV=hw:0") {
+		t.Errorf("sox device not passed in AUDIODEV")
+	}
+}
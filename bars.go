@@ -57,17 +57,7 @@ func (bv *BarsVisualizer) Draw(screen tcell.Screen, spectrum []float64, rawSampl
 		val := clamp(data[i], 0, 1)
 		x0 := i * (barW + gap)
 
-		if val >= bv.peaks[i] {
-			bv.peaks[i] = val
-			bv.peakVel[i] = 0
-		} else {
-			bv.peakVel[i] += cfg.Visual.PeakFallSpeed
-			bv.peaks[i] -= bv.peakVel[i]
-			if bv.peaks[i] < 0 {
-				bv.peaks[i] = 0
-				bv.peakVel[i] = 0
-			}
-		}
+		bv.updatePeak(i, val, cfg.Visual.PeakFallSpeed)
 
 		subHeight := int(val * float64(visH) * 8)
 
@@ -137,22 +127,120 @@ func (bv *BarsVisualizer) Draw(screen tcell.Screen, spectrum []float64, rawSampl
 			if showPeaks && bv.peaks[i] > 0.01 {
 				peakY := bottomY - int(bv.peaks[i]*float64(visH))
 				if peakY >= 0 && peakY < drawH {
-					peakT := bv.peaks[i]
-					peakColor := scheme.At(peakT)
-					pr, pg, pb := colorToRGB(peakColor)
-					brightPeak := tcell.NewRGBColor(
-						int32(math.Min(float64(pr)*1.5, 255)),
-						int32(math.Min(float64(pg)*1.5, 255)),
-						int32(math.Min(float64(pb)*1.5, 255)),
-					)
-					st := tcell.StyleDefault.Foreground(brightPeak)
-					screen.SetContent(cx, peakY, '▔', nil, st)
+					screen.SetContent(cx, peakY, '▔', nil, peakStyle(scheme, bv.peaks[i]))
+				}
+			}
+		}
+	}
+}
+
+func (bv *BarsVisualizer) DrawStereo(screen tcell.Screen, spectra [][]float64, rawSamples [][]float64, w, h int, scheme ColorScheme, cfg *Config) {
+	barW := cfg.Visual.BarWidth
+	gap := cfg.Visual.BarGap
+
+	drawH := h - 1
+	if !cfg.Visual.ShowStatus {
+		drawH = h
+	}
+
+	upH := drawH / 2
+	downH := drawH - upH
+	if upH < 1 {
+		return
+	}
+
+	numBars := (w + gap) / (barW + gap)
+	if numBars < 1 {
+		numBars = 1
+	}
+
+	left := resample(spectra[0], numBars)
+	right := resample(spectra[1], numBars)
+
+	if len(bv.peaks) != numBars*2 {
+		bv.peaks = make([]float64, numBars*2)
+		bv.peakVel = make([]float64, numBars*2)
+	}
+
+	for i := 0; i < numBars; i++ {
+		lv := clamp(left[i], 0, 1)
+		rv := clamp(right[i], 0, 1)
+		x0 := i * (barW + gap)
+
+		bv.updatePeak(i, lv, cfg.Visual.PeakFallSpeed)
+		bv.updatePeak(numBars+i, rv, cfg.Visual.PeakFallSpeed)
+
+		for bx := 0; bx < barW; bx++ {
+			cx := x0 + bx
+			if cx >= w {
+				break
+			}
+
+			drawStereoColumn(screen, cx, upH-1, -1, lv, upH, scheme)
+			drawStereoColumn(screen, cx, upH, 1, rv, downH, scheme)
+
+			if cfg.Visual.ShowPeaks {
+				if p := bv.peaks[i]; p > 0.01 {
+					if y := upH - 1 - int(p*float64(upH)); y >= 0 {
+						screen.SetContent(cx, y, '▔', nil, peakStyle(scheme, p))
+					}
+				}
+				if p := bv.peaks[numBars+i]; p > 0.01 {
+					if y := upH + int(p*float64(downH)); y < drawH {
+						screen.SetContent(cx, y, '▁', nil, peakStyle(scheme, p))
+					}
 				}
 			}
 		}
 	}
 }
 
+func (bv *BarsVisualizer) updatePeak(i int, val, fallSpeed float64) {
+	if val >= bv.peaks[i] {
+		bv.peaks[i] = val
+		bv.peakVel[i] = 0
+		return
+	}
+	bv.peakVel[i] += fallSpeed
+	bv.peaks[i] -= bv.peakVel[i]
+	if bv.peaks[i] < 0 {
+		bv.peaks[i] = 0
+		bv.peakVel[i] = 0
+	}
+}
+
+func drawStereoColumn(screen tcell.Screen, x, baseY, dir int, val float64, maxH int, scheme ColorScheme) {
+	subHeight := int(val * float64(maxH) * 8)
+	fullCells := subHeight / 8
+	remainder := subHeight % 8
+
+	for cy := 0; cy < fullCells && cy < maxH; cy++ {
+		t := float64(cy) / float64(maxH)
+		st := tcell.StyleDefault.Foreground(scheme.At(t))
+		screen.SetContent(x, baseY+dir*cy, '█', nil, st)
+	}
+
+	if remainder > 0 && fullCells < maxH {
+		t := float64(fullCells) / float64(maxH)
+		st := tcell.StyleDefault.Foreground(scheme.At(t))
+		if dir < 0 {
+			screen.SetContent(x, baseY-fullCells, blockChars[remainder], nil, st)
+		} else {
+			screen.SetContent(x, baseY+fullCells, blockChars[8-remainder], nil, st.Reverse(true))
+		}
+	}
+}
+
+func peakStyle(scheme ColorScheme, t float64) tcell.Style {
+	pr, pg, pb := colorToRGB(scheme.At(t))
+	brightPeak := tcell.NewRGBColor(
+		int32(math.Min(float64(pr)*1.5, 255)),
+		int32(math.Min(float64(pg)*1.5, 255)),
+		int32(math.Min(float64(pb)*1.5, 255)),
+	)
+	return tcell.StyleDefault.Foreground(brightPeak)
+}
+
 func colorToRGB(c tcell.Color) (int32, int32, int32) {
 	r, g, b := c.RGB()
 	return r, g, b
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+type CaptureState int
+
+const (
+	CaptureConnected CaptureState = iota
+	CaptureReconnecting
+	CaptureFailed
+)
+
+func (s CaptureState) String() string {
+	switch s {
+	case CaptureConnected:
+		return "connected"
+	case CaptureReconnecting:
+		return "reconnecting"
+	default:
+		return "failed"
+	}
+}
+
+const (
+	captureStallTimeout  = 2 * time.Second
+	captureMinBackoff    = 250 * time.Millisecond
+	captureMaxBackoff    = 5 * time.Second
+	captureFailThreshold = 5
+)
+
+type Capture struct {
+	backend    CaptureBackend
+	requested  string
+	latency    time.Duration
+	sampleRate int
+	autoRate   bool
+	channels   int
+	stream     *pcmStream
+	mu         sync.Mutex
+	cmd        *exec.Cmd
+	device     string
+	state      CaptureState
+	started    time.Time
+	closed     bool
+	done       chan struct{}
+	notices    chan string
+}
+
+// NewCapture starts capturing from device, or the backend's default when
+// device is empty. A sampleRate or channels of 0 uses the device's native
+// spec where the backend can tell, and 44100Hz stereo otherwise. An auto
+// rate is probed again whenever the capture restarts.
+func NewCapture(backend CaptureBackend, device string, latency time.Duration, sampleRate, bufferSize, hopSize, channels int) (*Capture, error) {
+	c := &Capture{
+		backend:    backend,
+		requested:  device,
+		latency:    latency,
+		sampleRate: sampleRate,
+		autoRate:   sampleRate == 0,
+		channels:   channels,
+		done:       make(chan struct{}),
+		notices:    make(chan string, 4),
+	}
+	if channels == 0 {
+		c.channels = fallbackChannels
+		if _, native, err := c.probe(); err == nil {
+			c.channels = native
+		}
+	}
+	c.stream = newPCMStream(mustSampleFormat("f32le"), c.channels, bufferSize, hopSize)
+
+	if err := c.start(); err != nil {
+		return nil, err
+	}
+
+	go c.supervise()
+	go c.watchdog()
+	if w, ok := backend.(DefaultWatcher); ok && device == "" {
+		go w.WatchDefault(c.done, c.defaultChanged)
+	}
+	return c, nil
+}
+
+func (c *Capture) start() error {
+	device := c.requested
+	if device == "" {
+		var err error
+		if device, err = c.backend.DefaultDevice(); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	latency, rate := c.latency, c.sampleRate
+	c.mu.Unlock()
+	if c.autoRate {
+		rate = fallbackSampleRate
+		if native, _, err := c.probeDevice(device); err == nil {
+			rate = native
+		}
+	}
+	cmd := c.backend.Command(device, rate, c.channels, latency)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", c.backend.Name(), err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("capture closed")
+	}
+	c.cmd = cmd
+	c.device = device
+	c.sampleRate = rate
+	c.state = CaptureConnected
+	c.started = time.Now()
+	c.stream.touch()
+	go func() {
+		_ = c.stream.readFrom(stdout)
+		_ = cmd.Wait()
+		c.exited(cmd)
+	}()
+	return nil
+}
+
+func (c *Capture) exited(cmd *exec.Cmd) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cmd == cmd && !c.closed {
+		c.cmd = nil
+		c.state = CaptureReconnecting
+		c.stream.silence()
+	}
+}
+
+func (c *Capture) supervise() {
+	ticker := time.NewTicker(captureMinBackoff)
+	defer ticker.Stop()
+
+	backoff := captureMinBackoff
+	failures := 0
+	var retryAt time.Time
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		c.mu.Lock()
+		alive := c.cmd != nil
+		started := c.started
+		c.mu.Unlock()
+
+		if alive {
+			if time.Since(started) > captureMaxBackoff {
+				backoff = captureMinBackoff
+				failures = 0
+			}
+			retryAt = time.Time{}
+			continue
+		}
+
+		if failures >= captureFailThreshold {
+			c.setState(CaptureFailed)
+		}
+		if retryAt.IsZero() {
+			retryAt = time.Now().Add(backoff)
+		}
+		if time.Now().Before(retryAt) {
+			continue
+		}
+
+		_ = c.start()
+		failures++
+		retryAt = time.Time{}
+		backoff *= 2
+		if backoff > captureMaxBackoff {
+			backoff = captureMaxBackoff
+		}
+	}
+}
+
+func (c *Capture) watchdog() {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		if c.stream.idleFor() > captureStallTimeout {
+			c.restart()
+		}
+	}
+}
+
+func (c *Capture) restart() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cmd != nil && c.cmd.Process != nil {
+		_ = c.cmd.Process.Kill()
+	}
+}
+
+func (c *Capture) probe() (rate, channels int, err error) {
+	device := c.requested
+	if device == "" {
+		if device, err = c.backend.DefaultDevice(); err != nil {
+			return 0, 0, err
+		}
+	}
+	return c.probeDevice(device)
+}
+
+func (c *Capture) probeDevice(device string) (rate, channels int, err error) {
+	prober, ok := c.backend.(SpecProber)
+	if !ok {
+		return 0, 0, fmt.Errorf("%s cannot report the native sample spec", c.backend.Name())
+	}
+	return prober.NativeSpec(device)
+}
+
+// SetLatency changes the backend buffering, restarting the capture process
+// if it differs.
+func (c *Capture) SetLatency(latency time.Duration) {
+	c.mu.Lock()
+	changed := latency != c.latency
+	c.latency = latency
+	c.mu.Unlock()
+	if changed {
+		c.restart()
+	}
+}
+
+func (c *Capture) defaultChanged() {
+	device, err := c.backend.DefaultDevice()
+	if err != nil || device == c.Device() {
+		return
+	}
+	c.restart()
+	msg := "Default sink changed: " + strings.TrimSuffix(device, ".monitor")
+	if ab, ok := c.backend.(appBackend); ok {
+		msg = fmt.Sprintf("Following %s (stream #%s)", ab.app, device)
+	}
+	select {
+	case c.notices <- msg:
+	default:
+	}
+}
+
+func (c *Capture) Notices() <-chan string { return c.notices }
+
+func (c *Capture) setState(state CaptureState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state = state
+}
+
+func (c *Capture) State() CaptureState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+func (c *Capture) Backend() string { return c.backend.Name() }
+
+// App is the application being captured, if any.
+func (c *Capture) App() string {
+	if ab, ok := c.backend.(appBackend); ok {
+		return ab.app
+	}
+	return ""
+}
+
+func (c *Capture) Device() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.device
+}
+
+func (c *Capture) SampleRate() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sampleRate
+}
+
+func (c *Capture) Channels() int { return c.channels }
+
+func (c *Capture) Read() [][]float64 {
+	return c.stream.Read()
+}
+
+func (c *Capture) SampleCount() int64 {
+	return c.stream.SampleCount()
+}
+
+func (c *Capture) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.done)
+	cmd := c.cmd
+	c.mu.Unlock()
+
+	if cmd != nil && cmd.Process != nil {
+		_ = cmd.Process.Kill()
+	}
+}
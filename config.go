@@ -8,8 +8,50 @@ import (
 )
 
 type AudioConfig struct {
-	SampleRate int `yaml:"sample_rate"`
-	BufferSize int `yaml:"buffer_size"`
+	SampleRate      int            `yaml:"sample_rate"`
+	AutoRate        bool           `yaml:"-"`
+	AutoChannels    bool           `yaml:"-"`
+	BufferSize      int            `yaml:"buffer_size"`
+	HopSize         int            `yaml:"hop_size"`
+	Channels        int            `yaml:"channels"`
+	ChannelMode     string         `yaml:"channel_mode"`
+	File            string         `yaml:"file"`
+	Loop            bool           `yaml:"loop"`
+	Input           string         `yaml:"input"`
+	SourceCmd       string         `yaml:"source_cmd"`
+	Format          string         `yaml:"format"`
+	Backend         string         `yaml:"backend"`
+	Backends        []string       `yaml:"backends"`
+	Device          string         `yaml:"device"`
+	App             string         `yaml:"app"`
+	SinkInput       string         `yaml:"-"`
+	Listen          string         `yaml:"listen"`
+	URL             string         `yaml:"url"`
+	JitterMS        int            `yaml:"jitter_ms"`
+	LatencyOffsetMS int            `yaml:"latency_offset_ms"`
+	Mix             string         `yaml:"mix"`
+	Sources         []SourceConfig `yaml:"sources"`
+}
+
+type SourceConfig struct {
+	Name    string   `yaml:"name"`
+	Type    string   `yaml:"type"`
+	Device  string   `yaml:"device"`
+	App     string   `yaml:"app"`
+	Backend string   `yaml:"backend"`
+	Path    string   `yaml:"path"`
+	Loop    bool     `yaml:"loop"`
+	Command string   `yaml:"command"`
+	Format  string   `yaml:"format"`
+	Listen  string   `yaml:"listen"`
+	URL     string   `yaml:"url"`
+	Scene   string   `yaml:"scene"`
+	Signal  string   `yaml:"signal"`
+	Freq    float64  `yaml:"freq"`
+	Level   *float64 `yaml:"level"`
+	Gain    *float64 `yaml:"gain"`
+	Mute    bool     `yaml:"mute"`
+	Pan     float64  `yaml:"pan"`
 }
 
 type VisualConfig struct {
@@ -24,12 +66,46 @@ type VisualConfig struct {
 	ShowStatus    bool    `yaml:"show_status"`
 }
 
+type DSPConfig struct {
+	Scale      string  `yaml:"scale"`
+	MinFreq    float64 `yaml:"min_freq"`
+	MaxFreq    float64 `yaml:"max_freq"`
+	Window     string  `yaml:"window"`
+	KaiserBeta float64 `yaml:"kaiser_beta"`
+	Normalize  string  `yaml:"normalize"`
+	FloorDB    float64 `yaml:"floor_db"`
+	CeilingDB  float64 `yaml:"ceiling_db"`
+	GateDB     float64 `yaml:"gate_db"`
+	AGCAttack  float64 `yaml:"agc_attack"`
+	AGCRelease float64 `yaml:"agc_release"`
+}
+
+type RecordConfig struct {
+	Dir     string  `yaml:"dir"`
+	PreRoll float64 `yaml:"preroll"`
+}
+
+type IdleConfig struct {
+	Enabled   bool    `yaml:"enabled"`
+	Threshold float64 `yaml:"threshold_db"`
+	Hold      float64 `yaml:"hold"`
+	FPS       int     `yaml:"fps"`
+	Style     string  `yaml:"style"`
+}
+
 type Config struct {
 	Style       string       `yaml:"style"`
 	ColorScheme string       `yaml:"color_scheme"`
 	Audio       AudioConfig  `yaml:"audio"`
 	Visual      VisualConfig `yaml:"visual"`
+	DSP         DSPConfig    `yaml:"dsp"`
+	Record      RecordConfig `yaml:"record"`
+	Idle        IdleConfig   `yaml:"idle"`
 	DemoMode    bool         `yaml:"-"`
+	DemoScene   string       `yaml:"demo_scene"`
+	Signal      string       `yaml:"-"`
+	SignalFreq  float64      `yaml:"-"`
+	SignalLevel float64      `yaml:"-"`
 }
 
 func DefaultConfig() *Config {
@@ -37,8 +113,16 @@ func DefaultConfig() *Config {
 		Style:       "bars",
 		ColorScheme: "rainbow",
 		Audio: AudioConfig{
-			SampleRate: 44100,
-			BufferSize: 4096,
+			SampleRate:  0,
+			BufferSize:  4096,
+			HopSize:     512,
+			Channels:    0,
+			ChannelMode: "mono",
+			Format:      "f32le",
+			Backend:     "auto",
+			Backends:    append([]string(nil), defaultBackendOrder...),
+			JitterMS:    60,
+			Mix:         "sum",
 		},
 		Visual: VisualConfig{
 			FPS:           60,
@@ -51,6 +135,47 @@ func DefaultConfig() *Config {
 			Mirror:        false,
 			ShowStatus:    true,
 		},
+		DSP: DSPConfig{
+			Scale:      "log",
+			MinFreq:    defaultMinFreq,
+			MaxFreq:    defaultMaxFreq,
+			Window:     "hann",
+			KaiserBeta: 8.6,
+			Normalize:  "relative",
+			FloorDB:    -90,
+			CeilingDB:  0,
+			AGCAttack:  0.05,
+			AGCRelease: 4,
+		},
+		Record: RecordConfig{
+			Dir:     ".",
+			PreRoll: 10,
+		},
+		Idle: IdleConfig{
+			Enabled:   true,
+			Threshold: -60,
+			Hold:      10,
+			FPS:       4,
+			Style:     "clock",
+		},
+	}
+}
+
+const (
+	fallbackSampleRate = 44100
+	fallbackChannels   = 2
+)
+
+// applyAudioDefaults fills in a sample rate or channel count left at 0.
+// Live capture later swaps them for the source's native spec.
+func (c *Config) applyAudioDefaults() {
+	if c.Audio.SampleRate <= 0 {
+		c.Audio.SampleRate = fallbackSampleRate
+		c.Audio.AutoRate = true
+	}
+	if c.Audio.Channels <= 0 {
+		c.Audio.Channels = fallbackChannels
+		c.Audio.AutoChannels = true
 	}
 }
 
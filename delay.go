@@ -0,0 +1,33 @@
+package main
+
+import "time"
+
+type delayedFrames struct {
+	at     time.Time
+	frames [][]float64
+}
+
+// frameDelay is a timestamped queue of analysis windows, used to draw the
+// audio later than it was captured when the listener hears it late.
+type frameDelay struct {
+	queue []delayedFrames
+}
+
+// Delay queues frames captured at now and returns the newest window that is
+// at least delay old, or the oldest one held while the queue fills.
+func (fd *frameDelay) Delay(frames [][]float64, now time.Time, delay time.Duration) [][]float64 {
+	if delay <= 0 {
+		fd.queue = fd.queue[:0]
+		return frames
+	}
+	fd.queue = append(fd.queue, delayedFrames{at: now, frames: frames})
+
+	due := 0
+	for i, f := range fd.queue {
+		if now.Sub(f.at) >= delay {
+			due = i
+		}
+	}
+	fd.queue = fd.queue[due:]
+	return fd.queue[0].frames
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type SourceInfo struct {
+	Index string
+	Name  string
+	Spec  string
+	State string
+}
+
+func (si SourceInfo) IsMonitor() bool {
+	return strings.HasSuffix(si.Name, ".monitor")
+}
+
+func ListSources() ([]SourceInfo, error) {
+	out, err := exec.Command("pactl", "list", "short", "sources").Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list sources: %w", err)
+	}
+	return parseShortSources(string(out)), nil
+}
+
+func parseShortSources(out string) []SourceInfo {
+	var sources []SourceInfo
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		si := SourceInfo{Index: fields[0], Name: fields[1]}
+		if len(fields) > 3 {
+			si.Spec = fields[3]
+		}
+		if len(fields) > 4 {
+			si.State = fields[4]
+		}
+		sources = append(sources, si)
+	}
+	return sources
+}
+
+// parseSampleSpec reads a PulseAudio sample spec like
+// "float32le 2ch 48000Hz".
+func parseSampleSpec(spec string) (rate, channels int, err error) {
+	for _, f := range strings.Fields(spec) {
+		if v, ok := strings.CutSuffix(f, "Hz"); ok {
+			rate, _ = strconv.Atoi(v)
+		} else if v, ok := strings.CutSuffix(f, "ch"); ok {
+			channels, _ = strconv.Atoi(v)
+		}
+	}
+	if rate <= 0 || channels <= 0 {
+		return 0, 0, fmt.Errorf("cannot parse sample spec %q", spec)
+	}
+	return rate, channels, nil
+}
+
+// DevicePicker lists the PulseAudio sources followed by the applications
+// currently playing. selected indexes sources first, then apps.
+type DevicePicker struct {
+	sources    []SourceInfo
+	apps       []AppStream
+	selected   int
+	current    string
+	currentApp string
+	err        string
+}
+
+func NewDevicePicker(current, currentApp string) *DevicePicker {
+	dp := &DevicePicker{current: current, currentApp: currentApp}
+	sources, err := ListSources()
+	if err != nil {
+		dp.err = err.Error()
+		return dp
+	}
+	dp.sources = sources
+	dp.apps, _ = ListAppStreams()
+	for i, s := range sources {
+		if s.Name == current && currentApp == "" {
+			dp.selected = i
+		}
+	}
+	if currentApp != "" {
+		// an app capture's device is the sink input it is recording
+		stream := current
+		if !slices.ContainsFunc(dp.apps, func(a AppStream) bool { return a.Index == stream }) {
+			s, _ := matchApp(dp.apps, currentApp)
+			stream = s.Index
+		}
+		for i, a := range dp.apps {
+			if a.Index == stream {
+				dp.selected = len(dp.sources) + i
+			}
+		}
+	}
+	return dp
+}
+
+func (dp *DevicePicker) Move(delta int) {
+	n := len(dp.sources) + len(dp.apps)
+	if n == 0 {
+		return
+	}
+	dp.selected = (dp.selected + delta + n) % n
+}
+
+func (dp *DevicePicker) Selected() (SourceInfo, bool) {
+	if dp.selected >= len(dp.sources) {
+		return SourceInfo{}, false
+	}
+	return dp.sources[dp.selected], true
+}
+
+func (dp *DevicePicker) SelectedApp() (AppStream, bool) {
+	i := dp.selected - len(dp.sources)
+	if i < 0 || i >= len(dp.apps) {
+		return AppStream{}, false
+	}
+	return dp.apps[i], true
+}
+
+func (dp *DevicePicker) Draw(screen tcell.Screen, w, h int) {
+	var items []string
+	switch {
+	case dp.err != "":
+		items = []string{dp.err}
+	case len(dp.sources) == 0:
+		items = []string{"no sources found"}
+	}
+	for _, s := range dp.sources {
+		mark := "  "
+		if s.Name == dp.current {
+			mark = "● "
+		}
+		kind := "input"
+		if s.IsMonitor() {
+			kind = "monitor"
+		}
+		items = append(items, fmt.Sprintf("%s%-8s %s", mark, kind, s.Name))
+	}
+
+	selected := -1
+	if dp.err == "" && dp.selected < len(dp.sources) {
+		selected = dp.selected
+	}
+
+	if len(dp.apps) > 0 {
+		items = append(items, "", "playing applications")
+		if dp.selected >= len(dp.sources) {
+			selected = len(items) + dp.selected - len(dp.sources)
+		}
+	}
+	current, _ := matchApp(dp.apps, dp.currentApp)
+	for _, a := range dp.apps {
+		mark := "  "
+		if dp.currentApp != "" && a.Index == current.Index {
+			mark = "● "
+		}
+		state := "playing"
+		if a.Corked {
+			state = "paused"
+		}
+		items = append(items, fmt.Sprintf("%s%-8s %s", mark, state, a.Label()))
+	}
+	drawListOverlay(screen, w, h, "AUDIO SOURCES", items, selected, "↑/↓ select  ENTER use  ESC close")
+}
@@ -2,188 +2,382 @@ package main
 
 import (
 	"math"
-	"math/cmplx"
+	"time"
 )
 
 type Processor struct {
 	cfg        *Config
 	window     []float64
-	prevBands  []float64
+	windowName string
+	windowBeta float64
+	windowGain float64
+	plan       *fftPlan
+	windowed   []float64
+	magnitudes []float64
+	edges      []float64
+	centers    []float64
+	edgesScale string
+	edgesBands int
+	edgesLow   float64
+	edgesHigh  float64
+	agcPeak    float64
+	quiet      bool
+	quietMax   float64
+	agcLast    time.Time
+	prevBands  [][]float64
 	numBands   int
 	sampleRate float64
 }
 
-func NewProcessor(cfg *Config) *Processor {
-	bufSize := cfg.Audio.BufferSize
-	window := make([]float64, bufSize)
-	for i := range window {
-		window[i] = 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(bufSize-1)))
-	}
+var channelModes = []string{"mono", "left", "right", "mid", "side", "both"}
+
+var normalizeModes = []string{"relative", "absolute", "agc"}
+
+// agcMinPeak keeps the AGC from amplifying near silence up to full height.
+const agcMinPeak = 0.001
 
-	return &Processor{
+func NewProcessor(cfg *Config) *Processor {
+	p := &Processor{
 		cfg:        cfg,
-		window:     window,
 		numBands:   0,
 		sampleRate: float64(cfg.Audio.SampleRate),
 	}
+	p.updateWindow(cfg.Audio.BufferSize)
+	return p
 }
 
-func (p *Processor) Process(samples []float64, numBands int) []float64 {
-	n := nextPow2(len(samples))
-	if n < 64 {
-		n = 64
+// updateWindow rebuilds the analysis window when the configured window or
+// the frame length has changed.
+func (p *Processor) updateWindow(n int) {
+	name, beta := p.cfg.DSP.Window, p.cfg.DSP.KaiserBeta
+	if len(p.window) == n && name == p.windowName && beta == p.windowBeta {
+		return
 	}
+	p.window = makeWindow(name, n, beta)
+	p.windowName, p.windowBeta = name, beta
+	p.windowGain = coherentGain(p.window)
+}
 
-	windowed := make([]complex128, n)
-	for i := 0; i < n && i < len(samples); i++ {
-		w := 1.0
-		if i < len(p.window) {
-			w = p.window[i]
-		}
-		windowed[i] = complex(samples[i]*w, 0)
-	}
+func (p *Processor) SetSampleRate(rate int) {
+	p.sampleRate = float64(rate)
+}
 
-	spectrum := fft(windowed)
+func (p *Processor) SampleRate() int { return int(p.sampleRate) }
 
-	halfN := n / 2
-	magnitudes := make([]float64, halfN)
-	for i := 0; i < halfN; i++ {
-		magnitudes[i] = cmplx.Abs(spectrum[i]) / float64(n)
+func (p *Processor) SelectChannels(frames [][]float64) [][]float64 {
+	if len(frames) == 0 {
+		return [][]float64{nil}
+	}
+	left := frames[0]
+	right := left
+	if len(frames) > 1 {
+		right = frames[1]
 	}
 
-	if numBands <= 0 {
-		numBands = 64
+	switch p.cfg.Audio.ChannelMode {
+	case "left":
+		return [][]float64{left}
+	case "right":
+		return [][]float64{right}
+	case "mid":
+		return [][]float64{combineChannels(left, right, 0.5, 0.5)}
+	case "side":
+		return [][]float64{combineChannels(left, right, 0.5, -0.5)}
+	case "both":
+		return [][]float64{left, right}
+	default:
+		return [][]float64{mixDown(frames)}
 	}
-	bands := p.groupIntoBands(magnitudes, numBands)
+}
 
-	for i := range bands {
-		if bands[i] > 0 {
-			bands[i] = math.Log10(1 + bands[i]*9)
-		}
+// Process returns the band values for each signal along with the edge
+// frequencies of those bands, len(values)+1 of them.
+func (p *Processor) Process(signals [][]float64, numBands int) ([][]float64, []float64) {
+	if numBands <= 0 {
+		numBands = 64
 	}
 
-	if p.prevBands == nil || len(p.prevBands) != len(bands) {
-		p.prevBands = make([]float64, len(bands))
+	if len(p.prevBands) != len(signals) {
+		p.prevBands = make([][]float64, len(signals))
 	}
 
 	smoothing := p.cfg.Visual.Smoothing
-	for i := range bands {
-		if bands[i] >= p.prevBands[i] {
-			p.prevBands[i] = bands[i]*0.7 + p.prevBands[i]*0.3
-		} else {
-			p.prevBands[i] = bands[i]*(1-smoothing) + p.prevBands[i]*smoothing
+	maxVal := 0.001
+	for c, samples := range signals {
+		bands := p.analyze(samples, numBands)
+
+		prev := p.prevBands[c]
+		if len(prev) != len(bands) {
+			prev = make([]float64, len(bands))
+			p.prevBands[c] = prev
+		}
+
+		for i := range bands {
+			if bands[i] >= prev[i] {
+				prev[i] = bands[i]*0.7 + prev[i]*0.3
+			} else {
+				prev[i] = bands[i]*(1-smoothing) + prev[i]*smoothing
+			}
+			if prev[i] > maxVal {
+				maxVal = prev[i]
+			}
 		}
 	}
 
-	result := make([]float64, len(bands))
-	maxVal := 0.001
-	for _, v := range p.prevBands {
-		if v > maxVal {
-			maxVal = v
+	sens := p.cfg.Visual.Sensitivity
+	absolute := p.cfg.DSP.Normalize == "absolute"
+	switch {
+	case p.cfg.DSP.Normalize == "agc":
+		maxVal = p.updateAGC(maxVal)
+	case p.quiet:
+		maxVal = math.Max(maxVal, p.quietMax)
+	default:
+		p.quietMax = maxVal
+	}
+	results := make([][]float64, len(signals))
+	for c, prev := range p.prevBands {
+		result := make([]float64, len(prev))
+		for i := range prev {
+			if absolute {
+				result[i] = prev[i]
+				continue
+			}
+			result[i] = math.Min(prev[i]/maxVal, 1.0)
+			result[i] = math.Pow(result[i], 0.7)
+			result[i] = math.Min(result[i]*sens, 1.0)
 		}
+		results[c] = result
 	}
 
-	for i := range p.prevBands {
-		result[i] = math.Min(p.prevBands[i]/maxVal, 1.0)
-		result[i] = math.Pow(result[i], 0.7)
+	return results, p.bandEdges(numBands)
+}
+
+func (p *Processor) analyze(samples []float64, numBands int) []float64 {
+	n := max(nextPow2(len(samples)), 64)
+	if p.plan == nil || p.plan.n != n {
+		p.plan = newFFTPlan(n)
+		p.windowed = make([]float64, n)
+		p.magnitudes = make([]float64, n/2)
 	}
+	p.updateWindow(len(samples))
 
-	sens := p.cfg.Visual.Sensitivity
-	for i := range result {
-		result[i] = math.Min(result[i]*sens, 1.0)
+	for i, v := range samples {
+		p.windowed[i] = v * p.window[i]
+	}
+	clear(p.windowed[len(samples):])
+	spectrum := p.plan.Transform(p.windowed)
+
+	// divide out the window's coherent gain so that a sine reads the same
+	// height whichever window is used
+	scale := float64(max(len(samples), 1)) * p.windowGain
+	magnitudes := p.magnitudes
+	for i, c := range spectrum {
+		magnitudes[i] = math.Hypot(real(c), imag(c)) / scale
 	}
 
-	return result
+	bands := p.groupIntoBands(magnitudes, numBands)
+	if p.cfg.DSP.Normalize == "absolute" {
+		for i := range bands {
+			bands[i] = p.dbfsLevel(bands[i])
+		}
+		return bands
+	}
+
+	for i := range bands {
+		if bands[i] > 0 {
+			bands[i] = math.Log10(1 + bands[i]*9)
+		}
+	}
+
+	return bands
 }
 
 func (p *Processor) groupIntoBands(magnitudes []float64, numBands int) []float64 {
-	bands := make([]float64, numBands)
 	halfN := len(magnitudes)
 	freqRes := p.sampleRate / float64(halfN*2)
-
-	lowFreq := 30.0
-	highFreq := math.Min(p.sampleRate/2, 18000.0)
+	edges := p.bandEdges(numBands)
+	centers := p.BandCenters(numBands)
+	numBands = len(edges) - 1
+	bands := make([]float64, numBands)
+	// absolute levels read the loudest bin so a sine shows its true level
+	absolute := p.cfg.DSP.Normalize == "absolute"
 
 	for i := 0; i < numBands; i++ {
-		f0 := lowFreq * math.Pow(highFreq/lowFreq, float64(i)/float64(numBands))
-		f1 := lowFreq * math.Pow(highFreq/lowFreq, float64(i+1)/float64(numBands))
-
-		bin0 := int(f0 / freqRes)
-		bin1 := int(f1 / freqRes)
-
-		if bin0 >= halfN {
-			bin0 = halfN - 1
-		}
-		if bin1 >= halfN {
-			bin1 = halfN - 1
-		}
-		if bin1 < bin0 {
-			bin1 = bin0
-		}
-
-		sum := 0.0
-		count := 0
-		for j := bin0; j <= bin1; j++ {
-			if j < halfN {
-				sum += magnitudes[j]
+		lo, hi := edges[i]/freqRes, edges[i+1]/freqRes
+		if hi-lo < 1 {
+			// narrower than a bin: read the spectrum at the band's center
+			// rather than repeating the same bin for neighboring bands
+			bands[i] = spectrumAt(magnitudes, math.Min(centers[i]/freqRes, float64(halfN-1)))
+		} else {
+			// a bin belongs to the band its frequency falls in, taking a
+			// peak's frequency from between the bins around it so a tone
+			// near an edge lands on the right side of it
+			sum, peak, count := 0.0, 0.0, 0
+			for j := max(int(lo)-1, 0); j <= min(int(hi)+1, halfN-1); j++ {
+				pos, v := float64(j), magnitudes[j]
+				if isPeak(magnitudes, j) {
+					pos = peakPos(magnitudes, j)
+					v = spectrumAt(magnitudes, pos)
+				}
+				if pos < lo || pos >= hi {
+					continue
+				}
+				sum += v
+				peak = math.Max(peak, v)
 				count++
 			}
-		}
-		if count > 0 {
-			bands[i] = sum / float64(count)
+			switch {
+			case count == 0:
+				bands[i] = spectrumAt(magnitudes, math.Min(centers[i]/freqRes, float64(halfN-1)))
+			case absolute:
+				bands[i] = peak
+			default:
+				bands[i] = sum / float64(count)
+			}
 		}
 
-		weight := 1.0 + float64(i)/float64(numBands)*2.0
-		bands[i] *= weight
+		if !absolute {
+			weight := 1.0 + float64(i)/float64(numBands)*2.0
+			bands[i] *= weight
+		}
 	}
 
 	return bands
 }
 
-func fft(data []complex128) []complex128 {
-	n := len(data)
-	if n <= 1 {
-		result := make([]complex128, len(data))
-		copy(result, data)
-		return result
+// spectrumAt reads magnitudes at a fractional bin. Next to a peak it
+// follows a parabola fitted to the log magnitudes of the peak and its
+// neighbors, which matches a window's main lobe far better than a straight
+// line and reads higher than either bin for a tone that falls between them.
+func spectrumAt(magnitudes []float64, pos float64) float64 {
+	const tiny = 1e-12
+	last := len(magnitudes) - 1
+	j := max(0, min(int(pos), last))
+	k := min(j+1, last)
+	for _, m := range []int{j, k} {
+		if isPeak(magnitudes, m) {
+			a := math.Log(magnitudes[m-1] + tiny)
+			b := math.Log(magnitudes[m] + tiny)
+			c := math.Log(magnitudes[m+1] + tiny)
+			x := pos - float64(m)
+			return math.Max(math.Exp(b+x*(c-a)/2+x*x*(a-2*b+c)/2)-tiny, 0)
+		}
 	}
+	return lerp(magnitudes[j], magnitudes[k], pos-float64(j))
+}
+
+func isPeak(magnitudes []float64, j int) bool {
+	return j > 0 && j < len(magnitudes)-1 && magnitudes[j] > magnitudes[j-1] && magnitudes[j] >= magnitudes[j+1]
+}
 
-	bits := 0
-	for tmp := n; tmp > 1; tmp >>= 1 {
-		bits++
+// peakPos is where the parabola through the peak at bin j and its two
+// neighbors tops out.
+func peakPos(magnitudes []float64, j int) float64 {
+	const tiny = 1e-12
+	a := math.Log(magnitudes[j-1] + tiny)
+	b := math.Log(magnitudes[j] + tiny)
+	c := math.Log(magnitudes[j+1] + tiny)
+	d := a - 2*b + c
+	if d >= 0 {
+		return float64(j)
 	}
+	return float64(j) + math.Max(-0.5, math.Min(0.5*(a-c)/d, 0.5))
+}
+
+// SetQuiet tells the processor the input is below the idle threshold. While
+// quiet, relative levels stay scaled to the last loud frame and the AGC
+// holds its gain, so the bars settle instead of noise being normalized up
+// to full height.
+func (p *Processor) SetQuiet(quiet bool) {
+	p.quiet = quiet
+}
 
-	result := make([]complex128, n)
-	for i := 0; i < n; i++ {
-		j := bitReverse(i, bits)
-		result[j] = data[i]
+// updateAGC follows the frame's peak with separate attack and release time
+// constants and returns the running peak to normalize against. Unlike
+// relative mode, quiet passages only grow as fast as the release allows.
+func (p *Processor) updateAGC(peak float64) float64 {
+	now := time.Now()
+	dt := 0.0
+	if !p.agcLast.IsZero() {
+		dt = math.Min(now.Sub(p.agcLast).Seconds(), 0.25)
 	}
+	p.agcLast = now
 
-	for size := 2; size <= n; size *= 2 {
-		halfSize := size / 2
-		wBase := -2.0 * math.Pi / float64(size)
+	if p.quiet && peak < p.agcPeak {
+		// hold through silence instead of releasing up to full gain on
+		// the noise floor
+		return p.agcPeak
+	}
+	if p.agcPeak <= 0 {
+		p.agcPeak = peak
+	}
+	tau := p.cfg.DSP.AGCRelease
+	if peak > p.agcPeak {
+		tau = p.cfg.DSP.AGCAttack
+	}
+	coef := 1.0
+	if tau > 0 {
+		coef = 1 - math.Exp(-dt/tau)
+	}
+	p.agcPeak = math.Max(p.agcPeak+(peak-p.agcPeak)*coef, agcMinPeak)
+	return p.agcPeak
+}
 
-		for start := 0; start < n; start += size {
-			for i := 0; i < halfSize; i++ {
-				angle := wBase * float64(i)
-				w := cmplx.Rect(1, angle)
-				t := w * result[start+i+halfSize]
-				result[start+i+halfSize] = result[start+i] - t
-				result[start+i] = result[start+i] + t
-			}
-		}
+// AGCGain is the gain the AGC currently applies in dB, 0 outside agc mode.
+func (p *Processor) AGCGain() float64 {
+	if p.cfg.DSP.Normalize != "agc" || p.agcPeak <= 0 {
+		return 0
 	}
+	return -20 * math.Log10(p.agcPeak)
+}
 
-	return result
+// dbfsLevel maps a band magnitude to 0..1 between the configured floor and
+// ceiling in dBFS, where a full scale sine is 0dB. Sensitivity is applied as
+// a gain after the noise gate.
+func (p *Processor) dbfsLevel(mag float64) float64 {
+	if mag <= 0 {
+		return 0
+	}
+	db := 20 * math.Log10(2*mag)
+	if gate := p.cfg.DSP.GateDB; gate != 0 && db < gate {
+		return 0
+	}
+	db += 20 * math.Log10(p.cfg.Visual.Sensitivity)
+	floor, ceiling := p.cfg.DSP.FloorDB, p.cfg.DSP.CeilingDB
+	if ceiling <= floor {
+		return 0
+	}
+	return clamp((db-floor)/(ceiling-floor), 0, 1)
 }
 
-func bitReverse(x, bits int) int {
-	result := 0
-	for i := 0; i < bits; i++ {
-		result = (result << 1) | (x & 1)
-		x >>= 1
+// bandEdges returns numBands+1 edge frequencies for the configured scale,
+// or the scale's own count for octave bands. The result is cached.
+func (p *Processor) bandEdges(numBands int) []float64 {
+	scale := p.cfg.DSP.Scale
+	lowFreq, highFreq := freqRange(p.cfg.DSP.MinFreq, p.cfg.DSP.MaxFreq, p.sampleRate/2)
+	if p.edges != nil && scale == p.edgesScale && numBands == p.edgesBands && lowFreq == p.edgesLow && highFreq == p.edgesHigh {
+		return p.edges
 	}
-	return result
+
+	p.edges = scaleEdges(scale, lowFreq, highFreq, numBands)
+	p.centers = scaleCenters(scale, p.edges)
+	p.edgesScale, p.edgesBands, p.edgesLow, p.edgesHigh = scale, numBands, lowFreq, highFreq
+	return p.edges
+}
+
+// BandCenters returns the center frequency of each band, for labelling.
+func (p *Processor) BandCenters(numBands int) []float64 {
+	p.bandEdges(numBands)
+	return p.centers
+}
+
+func bandForFreq(edges []float64, freq float64) int {
+	for i := 0; i < len(edges)-1; i++ {
+		if freq < edges[i+1] {
+			return i
+		}
+	}
+	return len(edges) - 2
 }
 
 func nextPow2(n int) int {
@@ -193,3 +387,49 @@ func nextPow2(n int) int {
 	}
 	return p
 }
+
+func NextNormalizeMode(mode string) string {
+	for i, m := range normalizeModes {
+		if m == mode {
+			return normalizeModes[(i+1)%len(normalizeModes)]
+		}
+	}
+	return normalizeModes[0]
+}
+
+func NextChannelMode(mode string) string {
+	for i, m := range channelModes {
+		if m == mode {
+			return channelModes[(i+1)%len(channelModes)]
+		}
+	}
+	return channelModes[0]
+}
+
+func mixDown(frames [][]float64) []float64 {
+	if len(frames) == 0 {
+		return nil
+	}
+	result := make([]float64, len(frames[0]))
+	for _, ch := range frames {
+		for i := 0; i < len(result) && i < len(ch); i++ {
+			result[i] += ch[i]
+		}
+	}
+	for i := range result {
+		result[i] /= float64(len(frames))
+	}
+	return result
+}
+
+func combineChannels(a, b []float64, ga, gb float64) []float64 {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = a[i]*ga + b[i]*gb
+	}
+	return result
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func loudestBand(spectrum []float64) int {
+	loudest := 0
+	for i, v := range spectrum {
+		if v > spectrum[loudest] {
+			loudest = i
+		}
+	}
+	return loudest
+}
+
+func TestToneLandsInExpectedBand(t *testing.T) {
+	for _, mode := range []string{"relative", "absolute", "agc"} {
+		for _, freq := range []float64{50, 63, 100, 200, 440, 1000, 1234, 3000, 5000, 12000} {
+			cfg := DefaultConfig()
+			cfg.Audio.SampleRate = 44100
+			cfg.DSP.Normalize = mode
+			sg, err := NewSignalGenerator("sine", freq, -12, 44100, cfg.Audio.BufferSize, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			frames := [][]float64{make([]float64, cfg.Audio.BufferSize)}
+			sg.render(frames, 0)
+
+			want := sg.ExpectedFreqs()[0]
+			for _, numBands := range []int{32, 70, 150} {
+				spectra, edges := NewProcessor(cfg).Process(frames, numBands)
+				expected := bandForFreq(edges, want)
+				lo, hi := edges[expected], edges[expected+1]
+				if math.Min(want-lo, hi-want) < 0.05*(hi-lo) {
+					continue // a tone right on an edge may honestly show on either side
+				}
+				if got := loudestBand(spectra[0]); got != expected {
+					t.Errorf("%s %gHz at %d bands: loudest band %d (%.0f–%.0f), want %d (%.0f–%.0f)",
+						mode, freq, numBands, got, edges[got], edges[got+1], expected, edges[expected], edges[expected+1])
+				}
+			}
+		}
+	}
+}
+
+func TestAGCHoldsThroughSilence(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Audio.SampleRate = 44100
+	cfg.DSP.Normalize = "agc"
+	loud, err := NewSignalGenerator("sine", 1000, -6, 44100, cfg.Audio.BufferSize, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	music := [][]float64{make([]float64, cfg.Audio.BufferSize)}
+	loud.render(music, 0)
+	hiss, err := NewSignalGenerator("white", 0, -65, 44100, cfg.Audio.BufferSize, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	silence := [][]float64{make([]float64, cfg.Audio.BufferSize)}
+	hiss.render(silence, 0)
+
+	// each frame stands in for a quarter second, the longest step the AGC takes
+	step := func(p *Processor, frames [][]float64) []float64 {
+		p.agcLast = time.Now().Add(-250 * time.Millisecond)
+		spectra, _ := p.Process(frames, 64)
+		return spectra[0]
+	}
+	highest := func(bands []float64) float64 { return bands[loudestBand(bands)] }
+
+	for _, quiet := range []bool{false, true} {
+		p := NewProcessor(cfg)
+		for range 20 {
+			step(p, music)
+		}
+		gain := p.AGCGain()
+		p.SetQuiet(quiet)
+		var bands []float64
+		for range 80 {
+			bands = step(p, silence)
+		}
+		switch {
+		case quiet && (p.AGCGain() != gain || highest(bands) > 0.01):
+			t.Errorf("quiet: gain went from %.1fdB to %.1fdB, noise reads %.2f", gain, p.AGCGain(), highest(bands))
+		case !quiet && p.AGCGain() < gain+20:
+			t.Errorf("not quiet: gain only went from %.1fdB to %.1fdB, the release should have caught up", gain, p.AGCGain())
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import "math"
+
+// fftPlan computes the spectrum of n real samples by packing them into an
+// n/2 point complex FFT. Twiddles, the bit reversal table and the work
+// buffers are built once per size so repeated transforms don't allocate.
+type fftPlan struct {
+	n       int
+	rev     []int
+	twiddle []complex128 // e^(-2πik/(n/2)) for the complex stages
+	split   []complex128 // e^(-2πik/n) for untangling the packed result
+	buf     []complex128
+	out     []complex128
+}
+
+func newFFTPlan(n int) *fftPlan {
+	n = max(nextPow2(n), 4)
+	m := n / 2
+	bits := 0
+	for tmp := m; tmp > 1; tmp >>= 1 {
+		bits++
+	}
+
+	pl := &fftPlan{
+		n:       n,
+		rev:     make([]int, m),
+		twiddle: make([]complex128, m/2),
+		split:   make([]complex128, m),
+		buf:     make([]complex128, m),
+		out:     make([]complex128, m),
+	}
+	for i := range pl.rev {
+		pl.rev[i] = bitReverse(i, bits)
+	}
+	for k := range pl.twiddle {
+		s, c := math.Sincos(-2 * math.Pi * float64(k) / float64(m))
+		pl.twiddle[k] = complex(c, s)
+	}
+	for k := range pl.split {
+		s, c := math.Sincos(-2 * math.Pi * float64(k) / float64(n))
+		pl.split[k] = complex(c, s)
+	}
+	return pl
+}
+
+// Transform returns bins 0..n/2-1 of the DFT of in, zero padded or
+// truncated to n samples. The result is only valid until the next call.
+func (pl *fftPlan) Transform(in []float64) []complex128 {
+	m := pl.n / 2
+	buf := pl.buf
+	for i := 0; i < m; i++ {
+		var re, im float64
+		if 2*i < len(in) {
+			re = in[2*i]
+		}
+		if 2*i+1 < len(in) {
+			im = in[2*i+1]
+		}
+		buf[pl.rev[i]] = complex(re, im)
+	}
+
+	for size := 2; size <= m; size *= 2 {
+		half := size / 2
+		step := m / size
+		for start := 0; start < m; start += size {
+			for i := 0; i < half; i++ {
+				t := pl.twiddle[i*step] * buf[start+i+half]
+				buf[start+i+half] = buf[start+i] - t
+				buf[start+i] += t
+			}
+		}
+	}
+
+	// X[k] = (Z[k] + conj(Z[m-k]))/2 - i·w^k·(Z[k] - conj(Z[m-k]))/2
+	for k := 0; k < m; k++ {
+		z := buf[k]
+		zc := buf[(m-k)%m]
+		zc = complex(real(zc), -imag(zc))
+		even := (z + zc) * 0.5
+		odd := (z - zc) * complex(0, -0.5)
+		pl.out[k] = even + pl.split[k]*odd
+	}
+	return pl.out
+}
+
+func bitReverse(x, bits int) int {
+	result := 0
+	for i := 0; i < bits; i++ {
+		result = (result << 1) | (x & 1)
+		x >>= 1
+	}
+	return result
+}
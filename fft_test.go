@@ -0,0 +1,127 @@
+package main
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+// complexFFT is the radix-2 transform Processor used before fftPlan: the
+// real input is widened to complex and every twiddle is computed on the fly.
+func complexFFT(data []complex128) []complex128 {
+	n := len(data)
+	result := make([]complex128, n)
+	if n <= 1 {
+		copy(result, data)
+		return result
+	}
+	bits := 0
+	for tmp := n; tmp > 1; tmp >>= 1 {
+		bits++
+	}
+	for i := 0; i < n; i++ {
+		result[bitReverse(i, bits)] = data[i]
+	}
+	for size := 2; size <= n; size *= 2 {
+		half := size / 2
+		wBase := -2.0 * math.Pi / float64(size)
+		for start := 0; start < n; start += size {
+			for i := 0; i < half; i++ {
+				t := cmplx.Rect(1, wBase*float64(i)) * result[start+i+half]
+				result[start+i+half] = result[start+i] - t
+				result[start+i] += t
+			}
+		}
+	}
+	return result
+}
+
+func naiveDFT(in []float64, bins int) []complex128 {
+	n := len(in)
+	out := make([]complex128, bins)
+	for k := range out {
+		var sum complex128
+		for i, v := range in {
+			s, c := math.Sincos(-2 * math.Pi * float64(k*i%n) / float64(n))
+			sum += complex(v*c, v*s)
+		}
+		out[k] = sum
+	}
+	return out
+}
+
+func TestFFTPlanMatchesDFT(t *testing.T) {
+	signals := map[string]func(i, n int) float64{
+		"sine": func(i, n int) float64 { return math.Sin(2*math.Pi*3.3*float64(i)/float64(n)) + 0.25 },
+		"impulse": func(i, n int) float64 {
+			if i == 1 {
+				return 1
+			}
+			return 0
+		},
+		"dc": func(i, n int) float64 { return 0.5 },
+	}
+	for n := 4; n <= 4096; n *= 2 {
+		pl := newFFTPlan(n)
+		for name, gen := range signals {
+			in := make([]float64, n)
+			for i := range in {
+				in[i] = gen(i, n)
+			}
+			got := pl.Transform(in)
+			want := naiveDFT(in, n/2)
+			if len(got) != n/2 {
+				t.Fatalf("%s n=%d: %d bins, want %d", name, n, len(got), n/2)
+			}
+			tol := 1e-9 * float64(n)
+			for k := range want {
+				if cmplx.Abs(got[k]-want[k]) > tol {
+					t.Errorf("%s n=%d bin %d: got %v, want %v", name, n, k, got[k], want[k])
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestFFTPlanZeroPads(t *testing.T) {
+	in := []float64{1, -0.5, 0.25}
+	got := newFFTPlan(16).Transform(in)
+	want := naiveDFT(append(in, make([]float64, 13)...), 8)
+	for k := range want {
+		if cmplx.Abs(got[k]-want[k]) > 1e-12 {
+			t.Errorf("bin %d: got %v, want %v", k, got[k], want[k])
+		}
+	}
+}
+
+func benchSignal(n int) []float64 {
+	rng := rand.New(rand.NewSource(1))
+	in := make([]float64, n)
+	for i := range in {
+		in[i] = rng.Float64()*2 - 1
+	}
+	return in
+}
+
+func BenchmarkComplexFFT(b *testing.B) {
+	in := benchSignal(4096)
+	data := make([]complex128, len(in))
+	b.ReportAllocs()
+	for b.Loop() {
+		for i, v := range in {
+			data[i] = complex(v, 0)
+		}
+		complexFFT(data)
+	}
+}
+
+func BenchmarkPlannedFFT(b *testing.B) {
+	in := benchSignal(4096)
+	pl := newFFTPlan(len(in))
+	b.ReportAllocs()
+	for b.Loop() {
+		pl.Transform(in)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac"
+)
+
+func LoadFLAC(path string) ([][]float32, int, error) {
+	stream, err := flac.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer stream.Close()
+
+	info := stream.Info
+	scale := float32(int64(1) << (info.BitsPerSample - 1))
+	data := make([][]float32, info.NChannels)
+	for c := range data {
+		data[c] = make([]float32, 0, info.NSamples)
+	}
+
+	for {
+		frame, err := stream.ParseNext()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		// keep what decoded from a truncated file or one with trailing tags
+		if errors.Is(err, io.ErrUnexpectedEOF) && len(data[0]) > 0 {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("decoding flac: %w", err)
+		}
+		for c, sub := range frame.Subframes {
+			if c >= len(data) {
+				break
+			}
+			for _, s := range sub.Samples[:sub.NSamples] {
+				data[c] = append(data[c], float32(s)/scale)
+			}
+		}
+	}
+	return data, int(info.SampleRate), nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// idleCheckRate is how often audio is polled while the screensaver runs, so
+// it wakes within a frame or two of sound returning.
+const idleCheckRate = 20
+
+// IdleDetector tracks whether the input has stayed below a level for long
+// enough to count as silence.
+type IdleDetector struct {
+	threshold  float64
+	hold       time.Duration
+	quiet      bool
+	quietSince time.Time
+}
+
+func NewIdleDetector(cfg *Config) *IdleDetector {
+	return &IdleDetector{
+		threshold: math.Pow(10, cfg.Idle.Threshold/20),
+		hold:      time.Duration(cfg.Idle.Hold * float64(time.Second)),
+	}
+}
+
+// Update measures the most recent hop of frames and reports whether the
+// input is idle. Any sound above the threshold ends idleness at once.
+func (d *IdleDetector) Update(frames [][]float64, hop int) bool {
+	level := 0.0
+	for _, ch := range frames {
+		start := max(len(ch)-hop, 0)
+		sum := 0.0
+		for _, v := range ch[start:] {
+			sum += v * v
+		}
+		if n := len(ch) - start; n > 0 {
+			level = math.Max(level, math.Sqrt(sum/float64(n)))
+		}
+	}
+
+	if level >= d.threshold {
+		d.quiet = false
+		return false
+	}
+	if !d.quiet {
+		d.quiet = true
+		d.quietSince = time.Now()
+	}
+	return d.Idle()
+}
+
+// Quiet reports whether the last update was below the threshold, even if
+// the hold time has not run out yet.
+func (d *IdleDetector) Quiet() bool { return d.quiet }
+
+// Wake restarts the hold time, e.g. after a key press.
+func (d *IdleDetector) Wake() {
+	d.quietSince = time.Now()
+}
+
+func (d *IdleDetector) Idle() bool {
+	return d.quiet && time.Since(d.quietSince) >= d.hold
+}
+
+var clockDigits = map[rune][5]string{
+	'0': {"███", "█ █", "█ █", "█ █", "███"},
+	'1': {" █ ", "██ ", " █ ", " █ ", "███"},
+	'2': {"███", "  █", "███", "█  ", "███"},
+	'3': {"███", "  █", "███", "  █", "███"},
+	'4': {"█ █", "█ █", "███", "  █", "  █"},
+	'5': {"███", "█  ", "███", "  █", "███"},
+	'6': {"███", "█  ", "███", "█ █", "███"},
+	'7': {"███", "  █", "  █", "  █", "  █"},
+	'8': {"███", "█ █", "███", "█ █", "███"},
+	'9': {"███", "█ █", "███", "  █", "███"},
+	':': {"   ", " █ ", "   ", " █ ", "   "},
+}
+
+func drawScreensaver(screen tcell.Screen, w, h int, now time.Time, scheme ColorScheme, style string) {
+	t := float64(now.UnixMilli()) / 1000
+
+	if style == "gradient" {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				fx, fy := float64(x)/float64(w), float64(y)/float64(h)
+				v := 0.5 + 0.25*math.Sin(fx*4+t*0.1) + 0.25*math.Sin(fy*3-t*0.07+fx*2)
+				screen.SetContent(x, y, ' ', nil, tcell.StyleDefault.Background(dimmedColor(scheme.At(v), 0.25)))
+			}
+		}
+		return
+	}
+
+	text := now.Format("15:04")
+	width := len(text)*4 - 1
+	if w < width || h < 5 {
+		return
+	}
+	// drift slowly around the screen to avoid burn-in
+	x0 := int(float64(w-width) * (0.5 + 0.5*math.Sin(t/47)))
+	y0 := int(float64(h-5) * (0.5 + 0.5*math.Sin(t/31)))
+	st := tcell.StyleDefault.Foreground(dimmedColor(scheme.At(0.5+0.5*math.Sin(t/20)), 0.6))
+	for i, r := range text {
+		for row, line := range clockDigits[r] {
+			for col, ch := range line {
+				if ch != ' ' {
+					screen.SetContent(x0+i*4+col, y0+row, ch, nil, st)
+				}
+			}
+		}
+	}
+}
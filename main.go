@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"math"
 	"os"
 	"os/signal"
 	"strings"
@@ -13,14 +14,47 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "send" {
+		if err := runSend(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	configFile := flag.String("config", "", "Path to config file (default: ~/.config/audiovis/config.yaml)")
 	style := flag.String("style", "", "Visualization style: bars, wave, spectrum, circle, fire")
 	colorScheme := flag.String("colors", "", "Color scheme: rainbow, fire, ocean, neon, pastel, matrix, sunset, aurora")
 	sensitivity := flag.Float64("sensitivity", 0, "Audio sensitivity multiplier (default: 1.0)")
-	demo := flag.Bool("demo", false, "Demo mode with synthetic audio (no audio input needed)")
+	demo := &demoFlag{}
+	flag.Var(demo, "demo", "Demo mode with synthetic audio, optionally followed by a scene name")
+	signalKind := flag.String("signal", "", "Test signal: sine, sweep, white, pink, impulse, multi")
+	signalFreq := flag.Float64("signal-freq", 1000, "Test signal frequency in Hz")
+	signalLevel := flag.Float64("signal-level", -12, "Test signal level in dBFS")
+	record := flag.String("record", "", "Record the visualized audio to a WAV file")
 	listStyles := flag.Bool("list", false, "List available styles and color schemes")
 	fps := flag.Int("fps", 0, "Target frames per second (default: 60)")
+	channelMode := flag.String("channel-mode", "", "Channel mode: mono, left, right, mid, side, both")
+	file := flag.String("file", "", "Play and visualize a WAV, FLAC or Ogg Vorbis file instead of capturing")
+	loop := flag.Bool("loop", false, "Loop the file given with --file")
+	input := flag.String("input", "", "Read raw PCM from a file or named pipe (- for stdin)")
+	sourceCmd := flag.String("source-cmd", "", "Read raw PCM from the stdout of a shell command")
+	format := flag.String("format", "", "Raw PCM sample format: u8, s16le, s24le, s32le, f32le, f64le")
+	channels := flag.Int("channels", 0, "Channel count (default: the capture source's, else 2)")
+	rate := flag.Int("rate", 0, "Sample rate in Hz (default: the capture source's native rate, else 44100)")
+	backend := flag.String("backend", "", "Capture backend: auto, parec, pw-record, arecord, sox")
+	device := flag.String("device", "", "Capture device/source (default: monitor of the default sink)")
+	app := flag.String("app", "", "Capture only one application's playback, by name or PID (needs parec)")
+	listen := flag.String("listen", "", "Receive PCM from aviz send on udp://[host]:port or tcp://[host]:port")
+	streamURL := flag.String("url", "", "Visualize an HTTP/Icecast MP3 stream")
+	latencyOffset := flag.Int("latency-offset", 0, "Delay the visuals by this many ms (negative lowers capture latency)")
+	listDevices := flag.Bool("list-devices", false, "List available PulseAudio sources")
 	flag.Parse()
+	if demo.enabled && demo.scene == "" && flag.NArg() > 0 {
+		// parsing stops at the scene name, so carry on with the flags after it
+		demo.scene = flag.Arg(0)
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
 
 	if *listStyles {
 		fmt.Println("╔══════════════════════════════════════════╗")
@@ -38,10 +72,39 @@ func main() {
 		for _, name := range AllSchemeNames() {
 			fmt.Printf("║    %-38s║\n", name)
 		}
+		fmt.Println("║                                          ║")
+		fmt.Println("║  Demo Scenes:                            ║")
+		for _, name := range SceneNames() {
+			fmt.Printf("║    %-38s║\n", name)
+		}
 		fmt.Println("╚══════════════════════════════════════════╝")
 		return
 	}
 
+	if *listDevices {
+		sources, err := ListSources()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, s := range sources {
+			kind := "input"
+			if s.IsMonitor() {
+				kind = "monitor"
+			}
+			fmt.Printf("%-4s %-8s %-10s %s\n", s.Index, kind, s.State, s.Name)
+		}
+		apps, _ := ListAppStreams()
+		for _, a := range apps {
+			state := "playing"
+			if a.Corked {
+				state = "paused"
+			}
+			fmt.Printf("%-4s %-8s %-10s %s\n", a.Index, "app", state, a.Label())
+		}
+		return
+	}
+
 	cfg := DefaultConfig()
 	cfg.TryLoadDefault()
 
@@ -64,7 +127,84 @@ func main() {
 	if *fps > 0 {
 		cfg.Visual.FPS = *fps
 	}
-	cfg.DemoMode = *demo
+	if *channelMode != "" {
+		cfg.Audio.ChannelMode = *channelMode
+	}
+	if *file != "" {
+		cfg.Audio.File = *file
+	}
+	if *loop {
+		cfg.Audio.Loop = true
+	}
+	if *input != "" {
+		cfg.Audio.Input = *input
+	}
+	if *sourceCmd != "" {
+		cfg.Audio.SourceCmd = *sourceCmd
+	}
+	if *format != "" {
+		cfg.Audio.Format = *format
+	}
+	if *channels > 0 {
+		cfg.Audio.Channels = *channels
+	}
+	if *rate > 0 {
+		cfg.Audio.SampleRate = *rate
+	}
+	if *backend != "" {
+		cfg.Audio.Backend = *backend
+	}
+	if *device != "" {
+		cfg.Audio.Device = *device
+	}
+	if *app != "" {
+		cfg.Audio.App = *app
+	}
+	if *listen != "" {
+		cfg.Audio.Listen = *listen
+	}
+	if *streamURL != "" {
+		cfg.Audio.URL = *streamURL
+	}
+	if *latencyOffset != 0 {
+		cfg.Audio.LatencyOffsetMS = *latencyOffset
+	}
+	cfg.applyAudioDefaults()
+	if *signalKind != "" {
+		cfg.Signal = *signalKind
+		cfg.SignalFreq = *signalFreq
+		cfg.SignalLevel = *signalLevel
+	}
+	if demo.enabled {
+		cfg.DemoMode = true
+		if demo.scene != "" {
+			cfg.DemoScene = demo.scene
+		}
+	}
+
+	audioErr := ""
+	audio, err := OpenAudioSource(cfg)
+	if err != nil {
+		if !capturesLive(cfg) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		audioErr = strings.ReplaceAll(err.Error(), "\n", "; ")
+		audio = NewDemoAudio(cfg.Audio.SampleRate, cfg.Audio.BufferSize, cfg.Audio.Channels)
+		cfg.DemoMode = true
+	}
+	defer func() { audio.Close() }()
+
+	// start recording before the screen takes over the terminal, so a bad
+	// path is reported where it can be read
+	recorder := NewRecorder(cfg)
+	if *record != "" {
+		if err := recorder.Start(*record); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	defer recorder.Stop()
 
 	screen, err := tcell.NewScreen()
 	if err != nil {
@@ -81,28 +221,20 @@ func main() {
 	screen.EnableMouse()
 	screen.Clear()
 
-	var audio AudioSource
-	audioErr := ""
-
-	if cfg.DemoMode {
-		audio = NewDemoAudio(cfg.Audio.SampleRate, cfg.Audio.BufferSize)
-	} else {
-		pa, err := NewPulseAudioCapture(cfg.Audio.SampleRate, cfg.Audio.BufferSize)
-		if err != nil {
-			audioErr = err.Error()
-			audio = NewDemoAudio(cfg.Audio.SampleRate, cfg.Audio.BufferSize)
-			cfg.DemoMode = true
-		} else {
-			audio = pa
-		}
-	}
-	defer audio.Close()
-
 	processor := NewProcessor(cfg)
 
 	vis := GetVisualizer(cfg.Style)
 	colors := GetColorScheme(cfg.ColorScheme)
 
+	idle := NewIdleDetector(cfg)
+	if cfg.Idle.FPS < 1 {
+		cfg.Idle.FPS = 1
+	}
+	sleeping := false
+	var lastSaver time.Time
+
+	var delay frameDelay
+
 	ticker := time.NewTicker(time.Second / time.Duration(cfg.Visual.FPS))
 	defer ticker.Stop()
 
@@ -127,8 +259,20 @@ func main() {
 
 	showHelp := false
 	paused := false
-	showAudioErr := audioErr != ""
-	audioErrTimeout := time.Now().Add(5 * time.Second)
+	var picker *DevicePicker
+	showSignal := true
+
+	notice := ""
+	noticeColor := tcell.ColorYellow
+	noticeUntil := time.Now().Add(5 * time.Second)
+	if audioErr != "" {
+		notice = "Audio: " + audioErr + " (using demo mode)"
+	}
+	notify := func(msg string, color tcell.Color) {
+		notice = msg
+		noticeColor = color
+		noticeUntil = time.Now().Add(3 * time.Second)
+	}
 	running := true
 	frameCount := 0
 
@@ -140,6 +284,54 @@ func main() {
 		case ev := <-eventCh:
 			switch ev := ev.(type) {
 			case *tcell.EventKey:
+				idle.Wake()
+				if picker != nil {
+					switch ev.Key() {
+					case tcell.KeyEscape:
+						picker = nil
+					case tcell.KeyUp:
+						picker.Move(-1)
+					case tcell.KeyDown:
+						picker.Move(1)
+					case tcell.KeyEnter:
+						// try the new source on a copy so a failed swap
+						// leaves cfg describing the source still playing
+						target, next := "", *cfg
+						if src, ok := picker.Selected(); ok {
+							next.Audio.Device = src.Name
+							next.Audio.App = ""
+							next.Audio.SinkInput = ""
+							target = src.Name
+						} else if app, ok := picker.SelectedApp(); ok {
+							next.Audio.App = app.Name
+							if app.Name == "" {
+								next.Audio.App = app.PID
+							}
+							next.Audio.SinkInput = app.Index
+							target = app.Label()
+						}
+						if target != "" {
+							capture, err := OpenCapture(&next)
+							if err != nil {
+								notify("Audio: "+strings.ReplaceAll(err.Error(), "\n", "; "), tcell.ColorYellow)
+							} else {
+								audio.Close()
+								audio = capture
+								cfg.Audio = next.Audio
+								cfg.DemoMode = false
+								processor.SetSampleRate(cfg.Audio.SampleRate)
+								recorder.SetSampleRate(cfg.Audio.SampleRate)
+								notify("Listening to "+target, tcell.NewRGBColor(100, 200, 255))
+							}
+						}
+						picker = nil
+					case tcell.KeyRune:
+						if ev.Rune() == 'd' || ev.Rune() == 'D' || ev.Rune() == 'q' {
+							picker = nil
+						}
+					}
+					continue
+				}
 				switch ev.Key() {
 				case tcell.KeyEscape:
 					if showHelp {
@@ -149,6 +341,22 @@ func main() {
 					}
 				case tcell.KeyCtrlC:
 					running = false
+				case tcell.KeyUp, tcell.KeyDown, tcell.KeyLeft, tcell.KeyRight:
+					zoom, pan := 1.0, 0.0
+					switch ev.Key() {
+					case tcell.KeyUp:
+						zoom = 0.8
+					case tcell.KeyDown:
+						zoom = 1.25
+					case tcell.KeyLeft:
+						pan = -0.1
+					case tcell.KeyRight:
+						pan = 0.1
+					}
+					nyquist := float64(cfg.Audio.SampleRate) / 2
+					lo, hi := freqRange(cfg.DSP.MinFreq, cfg.DSP.MaxFreq, nyquist)
+					cfg.DSP.MinFreq, cfg.DSP.MaxFreq = zoomRange(lo, hi, zoom, pan, nyquist)
+					notify("Range "+formatFreq(cfg.DSP.MinFreq)+"–"+formatFreq(cfg.DSP.MaxFreq), tcell.NewRGBColor(100, 200, 255))
 				case tcell.KeyRune:
 					switch ev.Rune() {
 					case 'q', 'Q':
@@ -181,6 +389,31 @@ func main() {
 						}
 					case 'm', 'M':
 						cfg.Visual.Mirror = !cfg.Visual.Mirror
+					case 'l', 'L':
+						cfg.Audio.ChannelMode = NextChannelMode(cfg.Audio.ChannelMode)
+					case 'b', 'B':
+						cfg.DSP.Scale = NextScale(cfg.DSP.Scale)
+						notify("Scale: "+cfg.DSP.Scale, tcell.NewRGBColor(100, 200, 255))
+					case 'a', 'A':
+						cfg.DSP.Normalize = NextNormalizeMode(cfg.DSP.Normalize)
+						msg := "Relative levels"
+						switch cfg.DSP.Normalize {
+						case "absolute":
+							msg = fmt.Sprintf("Absolute levels %.0f to %.0f dBFS", cfg.DSP.FloorDB, cfg.DSP.CeilingDB)
+						case "agc":
+							msg = "Automatic gain"
+						}
+						notify(msg, tcell.NewRGBColor(100, 200, 255))
+					case 'w', 'W':
+						cfg.DSP.Window = NextWindow(cfg.DSP.Window)
+						notify("Window: "+cfg.DSP.Window, tcell.NewRGBColor(100, 200, 255))
+					case 'd', 'D':
+						current, app := cfg.Audio.Device, cfg.Audio.App
+						if c, ok := audio.(*Capture); ok {
+							current, app = c.Device(), c.App()
+						}
+						picker = NewDevicePicker(current, app)
+						showHelp = false
 					case 'p', 'P':
 						cfg.Visual.ShowPeaks = !cfg.Visual.ShowPeaks
 					case 's', 'S':
@@ -202,6 +435,57 @@ func main() {
 						if cfg.Visual.BarWidth > 10 {
 							cfg.Visual.BarWidth = 10
 						}
+					case ',', '<':
+						if sk, ok := audio.(SeekableSource); ok {
+							sk.Seek(-5 * time.Second)
+						}
+					case '.', '>':
+						if sk, ok := audio.(SeekableSource); ok {
+							sk.Seek(5 * time.Second)
+						}
+					case '0':
+						if sk, ok := audio.(SeekableSource); ok {
+							sk.Restart()
+						}
+					case 'f', 'F', 'v', 'V':
+						if sg, ok := audio.(*SignalGenerator); ok {
+							switch ev.Rune() {
+							case 'f':
+								sg.AdjustFreq(-1)
+							case 'F':
+								sg.AdjustFreq(1)
+							case 'v':
+								sg.AdjustLevel(-1)
+							case 'V':
+								sg.AdjustLevel(1)
+							}
+						}
+					case 'g', 'G':
+						showSignal = !showSignal
+					case '{', '}':
+						step := 10
+						if ev.Rune() == '{' {
+							step = -10
+						}
+						minOffset := -int((captureDefaultLatency - captureMinLatency).Milliseconds())
+						cfg.Audio.LatencyOffsetMS = max(minOffset, min(cfg.Audio.LatencyOffsetMS+step, 2000))
+						if c, ok := audio.(*Capture); ok {
+							c.SetLatency(captureLatency(cfg.Audio.LatencyOffsetMS))
+						}
+						notify(fmt.Sprintf("Latency offset %+dms", cfg.Audio.LatencyOffsetMS), tcell.NewRGBColor(100, 200, 255))
+					case 'r', 'R':
+						if recorder.Recording() {
+							path := recorder.Path()
+							if err := recorder.Stop(); err != nil {
+								notify("Recording failed: "+err.Error(), tcell.ColorRed)
+							} else {
+								notify("Saved "+path, tcell.NewRGBColor(100, 200, 255))
+							}
+						} else if err := recorder.Start(""); err != nil {
+							notify(err.Error(), tcell.ColorRed)
+						} else {
+							notify("Recording to "+recorder.Path(), tcell.NewRGBColor(255, 90, 90))
+						}
 					}
 				}
 			case *tcell.EventResize:
@@ -209,6 +493,12 @@ func main() {
 			}
 
 		case <-ticker.C:
+			// pausing freezes the picture, a recording keeps going
+			frames := audio.Read()
+			if err := recorder.Feed(audio, frames); err != nil {
+				notify("Recording failed: "+err.Error(), tcell.ColorRed)
+			}
+
 			if paused {
 				w, h := screen.Size()
 				if w >= 2 && h >= 2 {
@@ -218,13 +508,48 @@ func main() {
 				continue
 			}
 
-			samples := audio.Read()
+			if n, ok := audio.(Notifier); ok {
+				select {
+				case msg := <-n.Notices():
+					notify(msg, tcell.NewRGBColor(100, 200, 255))
+				default:
+				}
+			}
 
 			w, h := screen.Size()
 			if w < 2 || h < 2 {
 				continue
 			}
 
+			_, testSignal := audio.(*SignalGenerator)
+			if cfg.Idle.Enabled && idle.Update(frames, cfg.Audio.HopSize) && !testSignal && !showHelp && picker == nil {
+				if !sleeping {
+					sleeping = true
+					ticker.Reset(time.Second / idleCheckRate)
+				}
+				if now := time.Now(); now.Sub(lastSaver) >= time.Second/time.Duration(cfg.Idle.FPS) {
+					lastSaver = now
+					screen.Clear()
+					drawScreensaver(screen, w, h, now, colors, cfg.Idle.Style)
+					screen.Show()
+				}
+				continue
+			}
+			if sleeping {
+				sleeping = false
+				ticker.Reset(time.Second / time.Duration(cfg.Visual.FPS))
+			}
+			processor.SetQuiet(cfg.Idle.Enabled && idle.Quiet())
+			offset := time.Duration(cfg.Audio.LatencyOffsetMS) * time.Millisecond
+			frames = delay.Delay(frames, time.Now(), offset)
+			if rr, ok := audio.(RateReporter); ok && rr.SampleRate() != processor.SampleRate() {
+				cfg.Audio.SampleRate = rr.SampleRate()
+				processor.SetSampleRate(cfg.Audio.SampleRate)
+				recorder.SetSampleRate(cfg.Audio.SampleRate)
+				notify(fmt.Sprintf("Sample rate %dHz", cfg.Audio.SampleRate), tcell.NewRGBColor(100, 200, 255))
+			}
+			signals := processor.SelectChannels(frames)
+
 			numBands := w
 			if vis.Name() == "bars" {
 				numBands = (w + cfg.Visual.BarGap) / (cfg.Visual.BarWidth + cfg.Visual.BarGap)
@@ -235,23 +560,37 @@ func main() {
 				numBands = 16
 			}
 
-			spectrum := processor.Process(samples, numBands)
+			spectra, edges := processor.Process(signals, numBands)
 
 			screen.Clear()
-			vis.Draw(screen, spectrum, samples, w, h, colors, cfg)
+			if sv, ok := vis.(StereoVisualizer); ok && len(spectra) == 2 {
+				sv.DrawStereo(screen, spectra, signals, w, h, colors, cfg)
+			} else if len(spectra) == 2 {
+				vis.Draw(screen, mixDown(spectra), mixDown(signals), w, h, colors, cfg)
+			} else {
+				vis.Draw(screen, spectra[0], signals[0], w, h, colors, cfg)
+			}
 
 			if cfg.Visual.ShowStatus {
-				drawStatusBar(screen, w, h, vis.Name(), colors.Name, cfg)
+				drawStatusBar(screen, w, h, vis.Name(), colors.Name, cfg, audio, recorder, processor)
 			}
 
 			if showHelp {
 				drawHelpOverlay(screen, w, h)
 			}
 
-			if showAudioErr && time.Now().Before(audioErrTimeout) {
-				drawNotification(screen, w, h, "Audio: "+audioErr+" (using demo mode)", tcell.ColorYellow)
+			if sg, ok := audio.(*SignalGenerator); ok && showSignal && !showHelp {
+				drawSignalOverlay(screen, w, h, sg, edges, spectra[0])
+			}
+
+			if picker != nil {
+				picker.Draw(screen, w, h)
+			}
+
+			if notice != "" && time.Now().Before(noticeUntil) {
+				drawNotification(screen, w, h, notice, noticeColor)
 			} else {
-				showAudioErr = false
+				notice = ""
 			}
 
 			screen.Show()
@@ -262,7 +601,7 @@ func main() {
 	close(quitEventLoop)
 }
 
-func drawStatusBar(screen tcell.Screen, w, h int, styleName, colorName string, cfg *Config) {
+func drawStatusBar(screen tcell.Screen, w, h int, styleName, colorName string, cfg *Config, audio AudioSource, rec *Recorder, p *Processor) {
 	y := h - 1
 
 	barStyle := tcell.StyleDefault.
@@ -272,29 +611,97 @@ func drawStatusBar(screen tcell.Screen, w, h int, styleName, colorName string, c
 	if cfg.DemoMode {
 		mode = "♪ DEMO"
 	}
+	if sa, ok := audio.(*SceneAudio); ok {
+		mode = "♪ DEMO:" + sa.Scene()
+	}
+	if sg, ok := audio.(*SignalGenerator); ok {
+		mode = "♪ SIGNAL " + sg.Describe()
+	}
+	if m, ok := audio.(*Mixer); ok {
+		mode = "♪ MIX " + m.Describe()
+	}
+	if c, ok := audio.(*Capture); ok {
+		source := c.Backend()
+		if app := c.App(); app != "" {
+			source += " " + app
+		}
+		switch c.State() {
+		case CaptureConnected:
+			mode = "♪ LIVE:" + source
+		case CaptureReconnecting:
+			mode = "♪ RECONNECTING:" + source
+		default:
+			mode = "♪ FAILED:" + source
+		}
+	}
+	if _, ok := audio.(*PipeSource); ok {
+		mode = "♪ PIPE"
+	}
+	if ns, ok := audio.(*NetSource); ok {
+		mode = "♪ NET " + ns.Describe()
+	}
+	if ss, ok := audio.(*StreamSource); ok {
+		mode = "♪ STREAM " + ss.Title()
+	}
+	if sk, ok := audio.(SeekableSource); ok {
+		mode = fmt.Sprintf("♪ FILE %s/%s", formatDuration(sk.Position()), formatDuration(sk.Duration()))
+	}
 
-	mirror := ""
+	var extras []string
 	if cfg.Visual.Mirror {
-		mirror = " │ mirror"
+		extras = append(extras, "mirror")
 	}
-
-	peaks := ""
 	if cfg.Visual.ShowPeaks {
-		peaks = " │ peaks"
+		extras = append(extras, "peaks")
+	}
+	if cfg.Audio.ChannelMode != "" && cfg.Audio.ChannelMode != "mono" {
+		extras = append(extras, "ch:"+cfg.Audio.ChannelMode)
+	}
+	if cfg.DSP.MinFreq != defaultMinFreq || cfg.DSP.MaxFreq != defaultMaxFreq {
+		lo, hi := freqRange(cfg.DSP.MinFreq, cfg.DSP.MaxFreq, float64(cfg.Audio.SampleRate)/2)
+		extras = append(extras, formatFreq(lo)+"–"+formatFreq(hi))
+	}
+	if cfg.DSP.Scale != "" && cfg.DSP.Scale != "log" {
+		extras = append(extras, cfg.DSP.Scale)
+	}
+	if cfg.DSP.Window != "" && cfg.DSP.Window != "hann" {
+		extras = append(extras, "win:"+cfg.DSP.Window)
+	}
+	if cfg.Audio.LatencyOffsetMS != 0 {
+		extras = append(extras, fmt.Sprintf("av:%+dms", cfg.Audio.LatencyOffsetMS))
+	}
+
+	if rec.Recording() {
+		lost := ""
+		if d := rec.Dropped(); d > 0 {
+			lost = fmt.Sprintf(" (%.1fs lost)", d.Seconds())
+		}
+		mode = "● REC " + formatDuration(rec.Elapsed()) + lost + " │ " + mode
 	}
 
-	status := fmt.Sprintf(" %s │ %s │ %s │ sens:%.1fx │ smooth:%.0f%%%s%s │ ?:help ",
+	sens := fmt.Sprintf("sens:%.1fx", cfg.Visual.Sensitivity)
+	switch cfg.DSP.Normalize {
+	case "absolute":
+		sens = fmt.Sprintf("dBFS gain:%+.1fdB", 20*math.Log10(cfg.Visual.Sensitivity))
+	case "agc":
+		sens = fmt.Sprintf("agc:%+.0fdB target:%+.1fdB", p.AGCGain(), 20*math.Log10(cfg.Visual.Sensitivity))
+	}
+
+	status := fmt.Sprintf(" %s │ %s │ %s │ %s │ smooth:%.0f%%",
 		mode,
 		strings.ToUpper(styleName),
 		colorName,
-		cfg.Visual.Sensitivity,
+		sens,
 		cfg.Visual.Smoothing*100,
-		mirror,
-		peaks,
 	)
+	for _, extra := range extras {
+		status += " │ " + extra
+	}
+	status += " │ ?:help "
 
 	accentStyle := barStyle.Foreground(tcell.NewRGBColor(100, 200, 255))
 	dimStyle := barStyle.Foreground(tcell.NewRGBColor(80, 80, 100))
+	recStyle := barStyle.Foreground(tcell.NewRGBColor(255, 70, 70)).Bold(true)
 
 	x := 0
 	for _, ch := range status {
@@ -306,6 +713,8 @@ func drawStatusBar(screen tcell.Screen, w, h int, styleName, colorName string, c
 			s = dimStyle
 		} else if ch == '♪' {
 			s = accentStyle
+		} else if ch == '●' {
+			s = recStyle
 		}
 		screen.SetContent(x, y, ch, nil, s)
 		x++
@@ -322,11 +731,25 @@ func drawHelpOverlay(screen tcell.Screen, w, h int) {
 		"║   n       Next visualization                 ║",
 		"║   c / C   Next / Previous color scheme       ║",
 		"║   + / -   Adjust sensitivity                 ║",
+		"║   a       Cycle relative / dBFS / AGC levels ║",
 		"║   m       Toggle mirror mode                 ║",
+		"║   l       Cycle channel mode                 ║",
+		"║   w       Cycle FFT window                   ║",
+		"║   b       Cycle frequency scale              ║",
+		"║   ↑ / ↓   Zoom frequency range in / out      ║",
+		"║   ← / →   Pan frequency range                ║",
+		"║   d       Pick audio source                  ║",
 		"║   p       Toggle peak indicators             ║",
 		"║   s       Cycle smoothing level              ║",
 		"║   [ / ]   Adjust bar width                   ║",
 		"║   SPACE   Pause / Resume                     ║",
+		"║   , / .   Seek file -5s / +5s                ║",
+		"║   0       Restart file                       ║",
+		"║   f / F   Signal frequency down / up         ║",
+		"║   v / V   Signal level down / up             ║",
+		"║   g       Toggle signal overlay              ║",
+		"║   r       Start / stop recording             ║",
+		"║   { }     Latency offset -/+ 10ms            ║",
 		"║                                              ║",
 		"║   ?/h     Toggle this help                   ║",
 		"║   q/ESC   Quit                               ║",
@@ -398,3 +821,30 @@ func drawNotification(screen tcell.Screen, w, h int, msg string, color tcell.Col
 		}
 	}
 }
+
+func formatDuration(d time.Duration) string {
+	secs := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+type demoFlag struct {
+	enabled bool
+	scene   string
+}
+
+func (d *demoFlag) String() string { return d.scene }
+
+func (d *demoFlag) Set(v string) error {
+	switch v {
+	case "true":
+		d.enabled = true
+	case "false":
+		d.enabled = false
+	default:
+		d.enabled = true
+		d.scene = v
+	}
+	return nil
+}
+
+func (d *demoFlag) IsBoolFlag() bool { return true }
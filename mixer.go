@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type mixerInput struct {
+	name   string
+	source AudioSource
+	gain   float64
+	pan    float64
+	mute   bool
+}
+
+type Mixer struct {
+	inputs     []mixerInput
+	separate   bool
+	bufferSize int
+	rate       int
+}
+
+func NewMixer(cfg *Config) (*Mixer, error) {
+	m := &Mixer{
+		separate:   cfg.Audio.Mix == "separate",
+		bufferSize: cfg.Audio.BufferSize,
+		rate:       cfg.Audio.SampleRate,
+	}
+	if cfg.Audio.Mix != "" && cfg.Audio.Mix != "sum" && !m.separate {
+		return nil, fmt.Errorf("unknown mix mode %q (want sum or separate)", cfg.Audio.Mix)
+	}
+
+	for i, sc := range cfg.Audio.Sources {
+		sub, err := sc.config(cfg)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("source %d: %w", i+1, err)
+		}
+		src, err := OpenAudioSource(sub)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("source %d: %w", i+1, err)
+		}
+
+		name := sc.Name
+		if name == "" {
+			name = sc.Type
+			if name == "" {
+				name = "capture"
+			}
+		}
+		gain := 1.0
+		if sc.Gain != nil {
+			gain = *sc.Gain
+		}
+		m.inputs = append(m.inputs, mixerInput{
+			name:   name,
+			source: src,
+			gain:   gain,
+			pan:    clamp(sc.Pan, -1, 1),
+			mute:   sc.Mute,
+		})
+	}
+	return m, nil
+}
+
+func (m *Mixer) Read() [][]float64 {
+	if m.separate {
+		out := make([][]float64, len(m.inputs))
+		for i, in := range m.inputs {
+			mono := m.conform(in.source, mixDown(in.source.Read()))
+			for j := range mono {
+				if in.mute {
+					mono[j] = 0
+				} else {
+					mono[j] *= in.gain
+				}
+			}
+			out[i] = mono
+		}
+		return out
+	}
+
+	out := makeFrames(2, m.bufferSize)
+	for _, in := range m.inputs {
+		frames := in.source.Read()
+		if in.mute || len(frames) == 0 {
+			continue
+		}
+		left := m.conform(in.source, frames[0])
+		right := left
+		if len(frames) > 1 {
+			right = m.conform(in.source, frames[1])
+		}
+		lg := in.gain * math.Min(1, 1-in.pan)
+		rg := in.gain * math.Min(1, 1+in.pan)
+		for j := 0; j < m.bufferSize; j++ {
+			out[0][j] += left[j] * lg
+			out[1][j] += right[j] * rg
+		}
+	}
+	return out
+}
+
+// SampleRate is the rate every input is mixed at.
+func (m *Mixer) SampleRate() int { return m.rate }
+
+// conform fits one channel read from src to the mixer's window. Sources
+// that set their own rate, like network and stream inputs, are resampled
+// to the mixer's rate; the rest already run at it.
+func (m *Mixer) conform(src AudioSource, data []float64) []float64 {
+	rr, ok := src.(RateReporter)
+	if !ok || rr.SampleRate() == m.rate || rr.SampleRate() <= 0 || len(data) == 0 {
+		return resample(data, m.bufferSize)
+	}
+	return resampleRate(data, float64(rr.SampleRate()), float64(m.rate), m.bufferSize)
+}
+
+// resampleRate returns the last n samples of data converted from one rate
+// to another. When data covers less time than n samples at the new rate
+// the start is left silent.
+func resampleRate(data []float64, from, to float64, n int) []float64 {
+	result := make([]float64, n)
+	last := float64(len(data) - 1)
+	step := from / to
+	for i := range result {
+		pos := last - float64(n-1-i)*step
+		if pos < 0 {
+			continue
+		}
+		idx := int(pos)
+		if idx >= len(data)-1 {
+			result[i] = data[len(data)-1]
+		} else {
+			result[i] = lerp(data[idx], data[idx+1], pos-float64(idx))
+		}
+	}
+	return result
+}
+
+func (m *Mixer) Describe() string {
+	names := make([]string, 0, len(m.inputs))
+	for _, in := range m.inputs {
+		name := in.name
+		if in.mute {
+			name += "(muted)"
+		}
+		names = append(names, name)
+	}
+	mode := "+"
+	if m.separate {
+		mode = "|"
+	}
+	return strings.Join(names, mode)
+}
+
+func (m *Mixer) Close() {
+	for _, in := range m.inputs {
+		in.source.Close()
+	}
+}
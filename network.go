@@ -0,0 +1,521 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every packet is a 16 byte header followed by interleaved PCM:
+//
+//	0  magic "AVZ1"
+//	4  sequence number (uint32, little endian)
+//	8  sample rate (uint32)
+//	12 channels (uint8)
+//	13 sample format, index into sampleFormats (uint8)
+//	14 frames in this packet (uint16)
+//
+// UDP sends one packet per datagram, TCP sends them back to back. Either
+// way a packet holds at most a second of audio and fits in a datagram.
+const (
+	netMagic         = "AVZ1"
+	netHeaderSize    = 16
+	netMaxPayload    = 1200
+	netMaxPacket     = 65536
+	netDefaultPort   = "7777"
+	netStaleTimeout  = time.Second
+	netPlayoutPeriod = 5 * time.Millisecond
+)
+
+type netHeader struct {
+	seq      uint32
+	rate     uint32
+	channels uint8
+	format   uint8
+	frames   uint16
+}
+
+func (h netHeader) marshal(payload []byte) []byte {
+	b := make([]byte, netHeaderSize+len(payload))
+	copy(b, netMagic)
+	binary.LittleEndian.PutUint32(b[4:], h.seq)
+	binary.LittleEndian.PutUint32(b[8:], h.rate)
+	b[12] = h.channels
+	b[13] = h.format
+	binary.LittleEndian.PutUint16(b[14:], h.frames)
+	copy(b[netHeaderSize:], payload)
+	return b
+}
+
+func parseNetHeader(b []byte) (netHeader, error) {
+	if len(b) < netHeaderSize || string(b[:4]) != netMagic {
+		return netHeader{}, errors.New("not an aviz packet")
+	}
+	h := netHeader{
+		seq:      binary.LittleEndian.Uint32(b[4:]),
+		rate:     binary.LittleEndian.Uint32(b[8:]),
+		channels: b[12],
+		format:   b[13],
+		frames:   binary.LittleEndian.Uint16(b[14:]),
+	}
+	if h.rate == 0 || h.channels == 0 || int(h.format) >= len(sampleFormats) {
+		return netHeader{}, errors.New("bad aviz packet header")
+	}
+	// the size comes from the peer, so check it before anything is allocated
+	if h.frames == 0 || uint32(h.frames) > h.rate || netHeaderSize+h.payloadSize() > netMaxPacket {
+		return netHeader{}, fmt.Errorf("bad aviz packet size (%d frames)", h.frames)
+	}
+	return h, nil
+}
+
+func (h netHeader) payloadSize() int {
+	return int(h.frames) * int(h.channels) * sampleFormats[h.format].Size
+}
+
+func sampleFormatIndex(name string) (uint8, error) {
+	f, err := ParseSampleFormat(name)
+	if err != nil {
+		return 0, err
+	}
+	for i := range sampleFormats {
+		if sampleFormats[i].Name == f.Name {
+			return uint8(i), nil
+		}
+	}
+	return 0, err
+}
+
+// parseNetAddr splits udp://host:port or tcp://host:port. Without a scheme
+// the address is UDP, and without a port the default one is used.
+func parseNetAddr(s string) (network, addr string, err error) {
+	network = "udp"
+	if i := strings.Index(s, "://"); i >= 0 {
+		network, s = s[:i], s[i+3:]
+	}
+	if network != "udp" && network != "tcp" {
+		return "", "", fmt.Errorf("unknown network %q (want udp or tcp)", network)
+	}
+	if _, _, err := net.SplitHostPort(s); err != nil {
+		s = net.JoinHostPort(s, netDefaultPort)
+	}
+	return network, s, nil
+}
+
+type netPacket struct {
+	header  netHeader
+	samples [][]float64
+}
+
+// jitterBuffer reorders packets by sequence number and releases them at the
+// stream's sample rate once delay worth of audio has arrived. Packets that
+// never show up are replaced with silence.
+type jitterBuffer struct {
+	delay   time.Duration
+	packets map[uint32]netPacket
+	held    int
+	next    uint32
+	playing bool
+	start   time.Time
+	played  int64
+	rate    int
+	frames  int
+	lost    int64
+	total   int64
+}
+
+func newJitterBuffer(delay time.Duration) *jitterBuffer {
+	return &jitterBuffer{delay: delay, packets: make(map[uint32]netPacket)}
+}
+
+func (jb *jitterBuffer) reset() {
+	jb.packets = make(map[uint32]netPacket)
+	jb.held = 0
+	jb.playing = false
+}
+
+func (jb *jitterBuffer) push(p netPacket) {
+	if int(p.header.rate) != jb.rate {
+		jb.reset()
+		jb.rate = int(p.header.rate)
+	}
+	if jb.playing && int32(p.header.seq-jb.next) < 0 {
+		if jb.next-p.header.seq < 64 {
+			return // too late, already concealed
+		}
+		// sequence jumped back a long way, the sender restarted
+		jb.reset()
+	}
+	if _, dup := jb.packets[p.header.seq]; dup {
+		return
+	}
+	jb.packets[p.header.seq] = p
+	jb.held += int(p.header.frames)
+	jb.frames = int(p.header.frames)
+
+	// the sender's clock runs fast, drop the oldest audio to keep up
+	limit := 4 * max(jb.delayFrames(), 4*jb.frames)
+	for jb.playing && jb.held > limit {
+		if old, ok := jb.packets[jb.next]; ok {
+			jb.held -= int(old.header.frames)
+			delete(jb.packets, jb.next)
+		}
+		jb.next++
+	}
+}
+
+func (jb *jitterBuffer) delayFrames() int {
+	return int(jb.delay.Seconds() * float64(jb.rate))
+}
+
+func (jb *jitterBuffer) pop(now time.Time) [][][]float64 {
+	if !jb.playing {
+		if len(jb.packets) == 0 || jb.held < jb.delayFrames() {
+			return nil
+		}
+		jb.playing = true
+		jb.start = now
+		jb.played = 0
+		first := true
+		for seq := range jb.packets {
+			if first || int32(seq-jb.next) < 0 {
+				jb.next = seq
+				first = false
+			}
+		}
+	}
+
+	var out [][][]float64
+	due := int64(now.Sub(jb.start).Seconds() * float64(jb.rate))
+	for jb.played < due {
+		if len(jb.packets) == 0 {
+			// underrun, wait for the buffer to fill up again
+			jb.playing = false
+			break
+		}
+		jb.total++
+		p, ok := jb.packets[jb.next]
+		jb.next++
+		if !ok {
+			jb.lost++
+			jb.played += int64(jb.frames)
+			out = append(out, nil)
+			continue
+		}
+		delete(jb.packets, p.header.seq)
+		jb.held -= int(p.header.frames)
+		jb.played += int64(p.header.frames)
+		out = append(out, p.samples)
+	}
+	return out
+}
+
+type NetSource struct {
+	stream   *pcmStream
+	network  string
+	addr     string
+	channels int
+	mu       sync.Mutex
+	jitter   *jitterBuffer
+	peer     string
+	conn     io.Closer
+	sender   net.Conn
+	closed   bool
+	rate     int
+	done     chan struct{}
+	notices  chan string
+}
+
+func NewNetSource(listen string, jitter time.Duration, sampleRate, bufferSize, hopSize, channels int) (*NetSource, error) {
+	network, addr, err := parseNetAddr(listen)
+	if err != nil {
+		return nil, err
+	}
+	ns := &NetSource{
+		stream:   newPCMStream(mustSampleFormat("f32le"), channels, bufferSize, hopSize),
+		network:  network,
+		addr:     addr,
+		channels: channels,
+		jitter:   newJitterBuffer(jitter),
+		rate:     sampleRate,
+		done:     make(chan struct{}),
+		notices:  make(chan string, 4),
+	}
+
+	if network == "udp" {
+		pc, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		ns.conn = pc
+		go ns.receiveUDP(pc)
+	} else {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		ns.conn = ln
+		go ns.acceptTCP(ln)
+	}
+	go ns.playout()
+	return ns, nil
+}
+
+func (ns *NetSource) receiveUDP(pc net.PacketConn) {
+	buf := make([]byte, netMaxPacket)
+	for {
+		n, from, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		h, err := parseNetHeader(buf[:n])
+		if err != nil || n-netHeaderSize < h.payloadSize() {
+			continue
+		}
+		ns.receive(from.String(), h, buf[netHeaderSize:netHeaderSize+h.payloadSize()])
+	}
+}
+
+func (ns *NetSource) acceptTCP(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		// a new sender replaces the old one
+		ns.mu.Lock()
+		if ns.sender != nil {
+			ns.sender.Close()
+		}
+		ns.sender = conn
+		ns.mu.Unlock()
+		go ns.receiveTCP(conn)
+	}
+}
+
+func (ns *NetSource) receiveTCP(conn net.Conn) {
+	defer conn.Close()
+	head := make([]byte, netHeaderSize)
+	for {
+		if _, err := io.ReadFull(conn, head); err != nil {
+			ns.notify("Sender disconnected: " + conn.RemoteAddr().String())
+			return
+		}
+		h, err := parseNetHeader(head)
+		if err != nil {
+			ns.notify("Dropped sender " + conn.RemoteAddr().String() + ": " + err.Error())
+			return
+		}
+		payload := make([]byte, h.payloadSize())
+		if _, err := io.ReadFull(conn, payload); err != nil {
+			ns.notify("Sender disconnected: " + conn.RemoteAddr().String())
+			return
+		}
+		ns.receive(conn.RemoteAddr().String(), h, payload)
+	}
+}
+
+func (ns *NetSource) receive(from string, h netHeader, payload []byte) {
+	samples := sampleFormats[h.format].Deinterleave(payload, int(h.channels))
+	if len(samples) != ns.channels {
+		samples = matchChannels(samples, ns.channels)
+	}
+
+	ns.mu.Lock()
+	newPeer := from != ns.peer
+	if newPeer {
+		ns.peer = from
+		ns.jitter.reset()
+	}
+	ns.jitter.push(netPacket{header: h, samples: samples})
+	ns.mu.Unlock()
+	if newPeer {
+		ns.notify("Receiving from " + from)
+	}
+	ns.stream.touch()
+}
+
+func (ns *NetSource) playout() {
+	ticker := time.NewTicker(netPlayoutPeriod)
+	defer ticker.Stop()
+	silent := true
+	for {
+		select {
+		case <-ns.done:
+			return
+		case now := <-ticker.C:
+			ns.mu.Lock()
+			chunks := ns.jitter.pop(now)
+			frames := ns.jitter.frames
+			ns.mu.Unlock()
+
+			for _, chunk := range chunks {
+				if chunk == nil {
+					chunk = makeFrames(ns.channels, frames)
+				}
+				ns.stream.ring.Write(chunk)
+				silent = false
+			}
+			if !silent && ns.stream.idleFor() > netStaleTimeout {
+				ns.stream.silence()
+				silent = true
+			}
+		}
+	}
+}
+
+func (ns *NetSource) notify(msg string) {
+	select {
+	case ns.notices <- msg:
+	default:
+	}
+}
+
+func (ns *NetSource) Notices() <-chan string { return ns.notices }
+
+func (ns *NetSource) Describe() string {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if ns.peer == "" || ns.stream.idleFor() > netStaleTimeout {
+		return fmt.Sprintf("%s %s waiting", ns.network, ns.addr)
+	}
+	loss := 0.0
+	if ns.jitter.total > 0 {
+		loss = float64(ns.jitter.lost) / float64(ns.jitter.total) * 100
+	}
+	host, _, _ := net.SplitHostPort(ns.peer)
+	return fmt.Sprintf("%s ← %s %dHz loss %.1f%%", ns.network, host, ns.jitter.rate, math.Round(loss*10)/10)
+}
+
+// SampleRate is the rate the sender is streaming at, or the configured
+// rate until something arrives.
+func (ns *NetSource) SampleRate() int {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if ns.jitter.rate > 0 {
+		return ns.jitter.rate
+	}
+	return ns.rate
+}
+
+func (ns *NetSource) Read() [][]float64 {
+	return ns.stream.Read()
+}
+
+func (ns *NetSource) SampleCount() int64 {
+	return ns.stream.SampleCount()
+}
+
+func (ns *NetSource) Close() {
+	ns.mu.Lock()
+	if ns.closed {
+		ns.mu.Unlock()
+		return
+	}
+	ns.closed = true
+	close(ns.done)
+	sender := ns.sender
+	ns.mu.Unlock()
+	ns.conn.Close()
+	if sender != nil {
+		sender.Close()
+	}
+}
+
+// netSender streams PCM to a NetSource. Over TCP it redials with backoff
+// whenever the connection drops; audio sent while disconnected is lost.
+type netSender struct {
+	network string
+	addr    string
+	format  SampleFormat
+	index   uint8
+	rate    int
+	conn    net.Conn
+	seq     uint32
+	backoff time.Duration
+	retryAt time.Time
+	logf    func(format string, args ...any)
+}
+
+func newNetSender(target, format string, sampleRate int, logf func(string, ...any)) (*netSender, error) {
+	network, addr, err := parseNetAddr(target)
+	if err != nil {
+		return nil, err
+	}
+	index, err := sampleFormatIndex(format)
+	if err != nil {
+		return nil, err
+	}
+	return &netSender{
+		network: network,
+		addr:    addr,
+		format:  sampleFormats[index],
+		index:   index,
+		rate:    sampleRate,
+		backoff: captureMinBackoff,
+		logf:    logf,
+	}, nil
+}
+
+func (s *netSender) dial() bool {
+	if s.conn != nil {
+		return true
+	}
+	if time.Now().Before(s.retryAt) {
+		return false
+	}
+	conn, err := net.DialTimeout(s.network, s.addr, 2*time.Second)
+	if err != nil {
+		s.logf("cannot reach %s: %v", s.addr, err)
+		s.retryAt = time.Now().Add(s.backoff)
+		s.backoff = min(s.backoff*2, captureMaxBackoff)
+		return false
+	}
+	s.logf("sending to %s %s", s.network, s.addr)
+	s.conn = conn
+	s.backoff = captureMinBackoff
+	return true
+}
+
+func (s *netSender) Send(frames [][]float64) {
+	if len(frames) == 0 || !s.dial() {
+		return
+	}
+	channels := len(frames)
+	perPacket := netMaxPayload / (s.format.Size * channels)
+	for start := 0; start < len(frames[0]); start += perPacket {
+		end := min(start+perPacket, len(frames[0]))
+		chunk := make([][]float64, channels)
+		for c := range frames {
+			chunk[c] = frames[c][start:end]
+		}
+		h := netHeader{
+			seq:      s.seq,
+			rate:     uint32(s.rate),
+			channels: uint8(channels),
+			format:   s.index,
+			frames:   uint16(end - start),
+		}
+		s.seq++
+		if _, err := s.conn.Write(h.marshal(s.format.Interleave(chunk))); err != nil {
+			if s.network == "udp" {
+				continue // nobody listening yet
+			}
+			s.logf("connection lost: %v", err)
+			s.Close()
+			return
+		}
+	}
+}
+
+func (s *netSender) Close() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPacket is a mono packet of 10 frames at 1kHz whose samples all hold
+// its sequence number, so the order it comes out in can be read back.
+func testPacket(seq uint32) netPacket {
+	samples := make([]float64, 10)
+	for i := range samples {
+		samples[i] = float64(seq)
+	}
+	return netPacket{
+		header:  netHeader{seq: seq, rate: 1000, channels: 1, frames: 10},
+		samples: [][]float64{samples},
+	}
+}
+
+// played lists the sequence numbers of the chunks pop released, -1 for a
+// concealed gap.
+func played(chunks [][][]float64) []int {
+	var seqs []int
+	for _, c := range chunks {
+		if c == nil {
+			seqs = append(seqs, -1)
+			continue
+		}
+		seqs = append(seqs, int(c[0][0]))
+	}
+	return seqs
+}
+
+func TestJitterBufferReorders(t *testing.T) {
+	jb := newJitterBuffer(30 * time.Millisecond)
+	for _, seq := range []uint32{2, 0, 1} {
+		jb.push(testPacket(seq))
+	}
+	start := time.Now()
+	if got := jb.pop(start); got != nil {
+		t.Fatalf("released %v before any time passed", played(got))
+	}
+	if got := played(jb.pop(start.Add(30 * time.Millisecond))); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Errorf("played %v, want 0 1 2", got)
+	}
+	if jb.lost != 0 || jb.total != 3 {
+		t.Errorf("lost %d of %d, want 0 of 3", jb.lost, jb.total)
+	}
+}
+
+func TestJitterBufferWaitsForDelay(t *testing.T) {
+	jb := newJitterBuffer(30 * time.Millisecond)
+	jb.push(testPacket(0))
+	jb.push(testPacket(1))
+	if got := jb.pop(time.Now()); got != nil || jb.playing {
+		t.Errorf("started with 20ms of a 30ms delay held")
+	}
+	jb.push(testPacket(2))
+	jb.pop(time.Now())
+	if !jb.playing {
+		t.Errorf("not playing with the delay held")
+	}
+}
+
+func TestJitterBufferConcealsLoss(t *testing.T) {
+	jb := newJitterBuffer(30 * time.Millisecond)
+	for _, seq := range []uint32{0, 1, 3, 4} {
+		jb.push(testPacket(seq))
+	}
+	start := time.Now()
+	jb.pop(start)
+	if got := played(jb.pop(start.Add(50 * time.Millisecond))); !slices.Equal(got, []int{0, 1, -1, 3, 4}) {
+		t.Errorf("played %v, want 0 1 gap 3 4", got)
+	}
+	if jb.lost != 1 || jb.total != 5 {
+		t.Errorf("lost %d of %d, want 1 of 5", jb.lost, jb.total)
+	}
+
+	// the missing packet turning up after its slot was concealed is dropped
+	jb.push(testPacket(2))
+	if len(jb.packets) != 0 || jb.held != 0 {
+		t.Errorf("late packet kept: %d packets, %d frames held", len(jb.packets), jb.held)
+	}
+}
+
+func TestJitterBufferSequenceReset(t *testing.T) {
+	jb := newJitterBuffer(20 * time.Millisecond)
+	for seq := uint32(1000); seq < 1003; seq++ {
+		jb.push(testPacket(seq))
+	}
+	start := time.Now()
+	jb.pop(start)
+	if got := played(jb.pop(start.Add(30 * time.Millisecond))); !slices.Equal(got, []int{1000, 1001, 1002}) {
+		t.Fatalf("played %v, want 1000 1001 1002", got)
+	}
+
+	// the sender restarted and counts from zero again
+	jb.push(testPacket(0))
+	if jb.playing || len(jb.packets) != 1 {
+		t.Fatalf("restarted sender not picked up: playing %v, %d packets held", jb.playing, len(jb.packets))
+	}
+	jb.push(testPacket(1))
+	restart := start.Add(time.Second)
+	jb.pop(restart)
+	if got := played(jb.pop(restart.Add(20 * time.Millisecond))); !slices.Equal(got, []int{0, 1}) {
+		t.Errorf("after restart played %v, want 0 1", got)
+	}
+}
+
+func TestJitterBufferRateChange(t *testing.T) {
+	jb := newJitterBuffer(20 * time.Millisecond)
+	jb.push(testPacket(0))
+	p := testPacket(1)
+	p.header.rate = 2000
+	jb.push(p)
+	if jb.rate != 2000 || len(jb.packets) != 1 {
+		t.Errorf("rate change kept old audio: rate %d, %d packets", jb.rate, len(jb.packets))
+	}
+}
+
+func TestParseNetHeader(t *testing.T) {
+	s16, err := sampleFormatIndex("s16le")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f64, err := sampleFormatIndex("f64le")
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := netHeader{seq: 7, rate: 48000, channels: 2, format: s16, frames: 300}
+	h, err := parseNetHeader(good.marshal(nil))
+	if err != nil || h != good {
+		t.Fatalf("round trip: %+v, %v", h, err)
+	}
+
+	tests := []struct {
+		name   string
+		header netHeader
+	}{
+		{"no frames", netHeader{rate: 48000, channels: 2, format: s16}},
+		{"over a second", netHeader{rate: 8000, channels: 1, format: s16, frames: 8001}},
+		{"bigger than a datagram", netHeader{rate: 96000, channels: 255, format: f64, frames: 60000}},
+		{"no rate", netHeader{channels: 2, format: s16, frames: 10}},
+		{"no channels", netHeader{rate: 48000, format: s16, frames: 10}},
+		{"unknown format", netHeader{rate: 48000, channels: 2, format: 200, frames: 10}},
+	}
+	for _, tt := range tests {
+		if _, err := parseNetHeader(tt.header.marshal(nil)); err == nil {
+			t.Errorf("%s: accepted", tt.name)
+		}
+	}
+	if _, err := parseNetHeader([]byte("GET / HTTP/1.1\r\n")); err == nil {
+		t.Errorf("accepted a non-aviz packet")
+	}
+}
+
+func TestNetSourceDropsOversizeSender(t *testing.T) {
+	ns, err := NewNetSource("tcp://127.0.0.1:0", 60*time.Millisecond, 48000, 4096, 512, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ns.Close()
+
+	conn, err := net.Dial("tcp", ns.conn.(net.Listener).Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	f64, _ := sampleFormatIndex("f64le")
+	h := netHeader{rate: 48000, channels: 255, format: f64, frames: 48000}
+	if _, err := conn.Write(h.marshal(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-ns.Notices():
+		if !strings.HasPrefix(msg, "Dropped sender") {
+			t.Errorf("notice %q, want the sender dropped", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("oversize packet header not rejected")
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jfreymuth/oggvorbis"
+)
+
+func LoadOgg(path string) ([][]float32, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	interleaved, format, err := oggvorbis.ReadAll(f)
+	if err != nil {
+		return nil, 0, fmt.Errorf("decoding ogg vorbis: %w", err)
+	}
+	if format.Channels < 1 {
+		return nil, 0, fmt.Errorf("ogg vorbis stream has %d channels", format.Channels)
+	}
+
+	n := len(interleaved) / format.Channels
+	data := make([][]float32, format.Channels)
+	for c := range data {
+		data[c] = make([]float32, n)
+		for i := range data[c] {
+			data[c][i] = interleaved[i*format.Channels+c]
+		}
+	}
+	return data, format.SampleRate, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func drawListOverlay(screen tcell.Screen, w, h int, title string, items []string, selected int, footer string) {
+	inner := utf8.RuneCountInString(title) + 4
+	for _, item := range append([]string{footer}, items...) {
+		if n := utf8.RuneCountInString(item) + 4; n > inner {
+			inner = n
+		}
+	}
+	if inner > w-2 {
+		inner = w - 2
+	}
+	// room for the two spaces of padding each side and a truncation mark
+	if inner < 5 {
+		return
+	}
+
+	pad := func(s string) string {
+		runes := []rune(s)
+		if len(runes) > inner-4 {
+			runes = append(runes[:inner-5], '…')
+		}
+		return "  " + string(runes) + strings.Repeat(" ", inner-4-len(runes)) + "  "
+	}
+
+	lines := []string{
+		"╔" + strings.Repeat("═", inner) + "╗",
+		"║" + pad(title) + "║",
+		"╠" + strings.Repeat("═", inner) + "╣",
+	}
+	first := len(lines)
+	for _, item := range items {
+		lines = append(lines, "║"+pad(item)+"║")
+	}
+	if footer != "" {
+		lines = append(lines, "╠"+strings.Repeat("═", inner)+"╣", "║"+pad(footer)+"║")
+	}
+	lines = append(lines, "╚"+strings.Repeat("═", inner)+"╝")
+
+	boxW := inner + 2
+	startX := (w - boxW) / 2
+	startY := (h - len(lines)) / 2
+	if startX < 0 {
+		startX = 0
+	}
+	if startY < 0 {
+		startY = 0
+	}
+
+	textStyle := tcell.StyleDefault.
+		Foreground(tcell.NewRGBColor(200, 200, 220))
+
+	borderStyle := tcell.StyleDefault.
+		Foreground(tcell.NewRGBColor(80, 140, 220))
+
+	titleStyle := tcell.StyleDefault.
+		Foreground(tcell.NewRGBColor(120, 200, 255)).
+		Bold(true)
+
+	selectedStyle := tcell.StyleDefault.
+		Foreground(tcell.NewRGBColor(20, 20, 30)).
+		Background(tcell.NewRGBColor(120, 200, 255))
+
+	for i, line := range lines {
+		y := startY + i
+		if y >= h {
+			break
+		}
+		x := startX
+		for j, ch := range []rune(line) {
+			if x >= w {
+				break
+			}
+			s := textStyle
+			switch {
+			case strings.ContainsRune("╔╗╚╝═║╠╣", ch):
+				s = borderStyle
+			case i == 1:
+				s = titleStyle
+			case selected >= 0 && i == first+selected && j > 0:
+				s = selectedStyle
+			}
+			screen.SetContent(x, y, ch, nil, s)
+			x++
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SampleFormat struct {
+	Name   string
+	Size   int
+	decode func(b []byte) float64
+	encode func(b []byte, v float64)
+}
+
+var sampleFormats = []SampleFormat{
+	{Name: "u8", Size: 1, decode: func(b []byte) float64 {
+		return (float64(b[0]) - 128) / 128
+	}, encode: func(b []byte, v float64) {
+		b[0] = uint8(quantize(v, 128) + 128)
+	}},
+	{Name: "s16le", Size: 2, decode: func(b []byte) float64 {
+		return float64(int16(binary.LittleEndian.Uint16(b))) / 32768
+	}, encode: func(b []byte, v float64) {
+		binary.LittleEndian.PutUint16(b, uint16(int16(quantize(v, 32768))))
+	}},
+	{Name: "s24le", Size: 3, decode: func(b []byte) float64 {
+		v := int32(b[0]) | int32(b[1])<<8 | int32(b[2])<<16
+		if v&0x800000 != 0 {
+			v -= 1 << 24
+		}
+		return float64(v) / 8388608
+	}, encode: func(b []byte, v float64) {
+		n := int32(quantize(v, 8388608))
+		b[0], b[1], b[2] = byte(n), byte(n>>8), byte(n>>16)
+	}},
+	{Name: "s32le", Size: 4, decode: func(b []byte) float64 {
+		return float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+	}, encode: func(b []byte, v float64) {
+		binary.LittleEndian.PutUint32(b, uint32(int32(quantize(v, 2147483648))))
+	}},
+	{Name: "f32le", Size: 4, decode: func(b []byte) float64 {
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}, encode: func(b []byte, v float64) {
+		binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+	}},
+	{Name: "f64le", Size: 8, decode: func(b []byte) float64 {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}, encode: func(b []byte, v float64) {
+		binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+	}},
+}
+
+// quantize scales v in [-1, 1] to a signed integer of the given full scale,
+// clipping rather than wrapping.
+func quantize(v, scale float64) int64 {
+	return int64(math.Max(-scale, math.Min(scale-1, math.Round(v*scale))))
+}
+
+func ParseSampleFormat(name string) (SampleFormat, error) {
+	for _, f := range sampleFormats {
+		if strings.EqualFold(f.Name, name) {
+			return f, nil
+		}
+	}
+	names := make([]string, len(sampleFormats))
+	for i, f := range sampleFormats {
+		names[i] = f.Name
+	}
+	return SampleFormat{}, fmt.Errorf("unknown sample format %q (want %s)", name, strings.Join(names, ", "))
+}
+
+func mustSampleFormat(name string) SampleFormat {
+	f, err := ParseSampleFormat(name)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func (f SampleFormat) Decode(b []byte) float64 {
+	return f.decode(b)
+}
+
+func (f SampleFormat) Encode(b []byte, v float64) {
+	f.encode(b, v)
+}
+
+func (f SampleFormat) Interleave(frames [][]float64) []byte {
+	if len(frames) == 0 {
+		return nil
+	}
+	channels := len(frames)
+	buf := make([]byte, len(frames[0])*channels*f.Size)
+	for i := range frames[0] {
+		for c := 0; c < channels; c++ {
+			off := (i*channels + c) * f.Size
+			f.encode(buf[off:off+f.Size], frames[c][i])
+		}
+	}
+	return buf
+}
+
+func (f SampleFormat) Deinterleave(buf []byte, channels int) [][]float64 {
+	frameSize := f.Size * channels
+	numFrames := len(buf) / frameSize
+	frames := makeFrames(channels, numFrames)
+	for i := 0; i < numFrames; i++ {
+		for c := 0; c < channels; c++ {
+			off := i*frameSize + c*f.Size
+			frames[c][i] = f.decode(buf[off : off+f.Size])
+		}
+	}
+	return frames
+}
+
+type pcmStream struct {
+	format     SampleFormat
+	channels   int
+	bufferSize int
+	hopSize    int
+	ring       *ringBuffer
+	mu         sync.Mutex
+	lastWrite  time.Time
+	readCount  int64
+}
+
+func newPCMStream(format SampleFormat, channels, bufferSize, hopSize int) *pcmStream {
+	if hopSize <= 0 || hopSize > bufferSize {
+		hopSize = bufferSize
+	}
+	return &pcmStream{
+		format:     format,
+		channels:   channels,
+		bufferSize: bufferSize,
+		hopSize:    hopSize,
+		ring:       newRingBuffer(channels, bufferSize),
+	}
+}
+
+func (ps *pcmStream) readFrom(r io.Reader) error {
+	frameSize := ps.format.Size * ps.channels
+	buf := make([]byte, ps.hopSize*frameSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n >= frameSize {
+			ps.ring.Write(ps.format.Deinterleave(buf[:n-n%frameSize], ps.channels))
+			ps.touch()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (ps *pcmStream) silence() {
+	ps.ring.Reset()
+}
+
+func (ps *pcmStream) touch() {
+	ps.mu.Lock()
+	ps.lastWrite = time.Now()
+	ps.mu.Unlock()
+}
+
+func (ps *pcmStream) idleFor() time.Duration {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return time.Since(ps.lastWrite)
+}
+
+func (ps *pcmStream) Read() [][]float64 {
+	frames, count := ps.ring.LatestAt(ps.bufferSize)
+	ps.mu.Lock()
+	ps.readCount = count
+	ps.mu.Unlock()
+	return frames
+}
+
+func (ps *pcmStream) SampleCount() int64 {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.readCount
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+type PipeSource struct {
+	stream *pcmStream
+	path   string
+	cmd    *exec.Cmd
+	mu     sync.Mutex
+	reader io.ReadCloser
+	fifo   bool
+	closed bool
+}
+
+func NewPipeSource(input, command string, format SampleFormat, channels, bufferSize, hopSize int) (*PipeSource, error) {
+	ps := &PipeSource{
+		stream: newPCMStream(format, channels, bufferSize, hopSize),
+		path:   input,
+	}
+
+	switch {
+	case command != "":
+		cmd := exec.Command("sh", "-c", command)
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start source command: %w", err)
+		}
+		ps.cmd = cmd
+		ps.reader = stdout
+	case input == "-":
+		ps.reader = os.Stdin
+	default:
+		info, err := os.Stat(input)
+		if err != nil {
+			return nil, err
+		}
+		ps.fifo = info.Mode()&os.ModeNamedPipe != 0
+	}
+
+	go ps.readLoop()
+	return ps, nil
+}
+
+func (ps *PipeSource) readLoop() {
+	for {
+		r := ps.currentReader()
+		if r == nil {
+			// opening a fifo blocks until a writer shows up
+			f, err := os.Open(ps.path)
+			if err != nil {
+				return
+			}
+			if !ps.setReader(f) {
+				f.Close()
+				return
+			}
+			r = f
+		}
+
+		_ = ps.stream.readFrom(r)
+		ps.stream.silence()
+
+		if !ps.fifo || !ps.setReader(nil) {
+			return
+		}
+		r.Close()
+	}
+}
+
+func (ps *PipeSource) currentReader() io.ReadCloser {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.reader
+}
+
+func (ps *PipeSource) setReader(r io.ReadCloser) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.closed {
+		return false
+	}
+	ps.reader = r
+	return true
+}
+
+func (ps *PipeSource) Read() [][]float64 {
+	return ps.stream.Read()
+}
+
+func (ps *PipeSource) SampleCount() int64 {
+	return ps.stream.SampleCount()
+}
+
+func (ps *PipeSource) Close() {
+	ps.mu.Lock()
+	ps.closed = true
+	r := ps.reader
+	ps.mu.Unlock()
+
+	if ps.cmd != nil && ps.cmd.Process != nil {
+		_ = ps.cmd.Process.Kill()
+		_ = ps.cmd.Wait()
+	}
+	if r != nil && r != os.Stdin {
+		r.Close()
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// sampleTap works out which samples in a window returned by Read have not
+// been seen before, using the source's sample counter when it has one and
+// the wall clock otherwise. New samples that no longer fit in the window,
+// because reads came too far apart, are counted in dropped.
+type sampleTap struct {
+	rate     float64
+	source   AudioSource
+	last     int64
+	lastTime time.Time
+	dropped  int64
+}
+
+func (t *sampleTap) fresh(src AudioSource, frames [][]float64) [][]float64 {
+	if len(frames) == 0 {
+		return nil
+	}
+	n := len(frames[0])
+
+	count := n
+	now := time.Now()
+	if sc, ok := src.(SampleCounter); ok {
+		total := sc.SampleCount()
+		if src == t.source && total >= t.last {
+			count = int(total - t.last)
+		}
+		t.last = total
+	} else if src == t.source {
+		count = int(now.Sub(t.lastTime).Seconds() * t.rate)
+		now = t.lastTime.Add(time.Duration(float64(count) / t.rate * float64(time.Second)))
+	}
+	t.source = src
+	t.lastTime = now
+
+	if count > n {
+		t.dropped += int64(count - n)
+		count = n
+	}
+	if count <= 0 {
+		return nil
+	}
+	result := make([][]float64, len(frames))
+	for c := range frames {
+		result[c] = frames[c][n-count:]
+	}
+	return result
+}
+
+type Recorder struct {
+	dir      string
+	rate     int
+	srcRate  int
+	channels int
+	tap      sampleTap
+	preroll  *ringBuffer
+	writer   *wavWriter
+	path     string
+	dropped  int64
+	err      error
+}
+
+func NewRecorder(cfg *Config) *Recorder {
+	size := int(cfg.Record.PreRoll * float64(cfg.Audio.SampleRate))
+	if size < 1 {
+		size = 1
+	}
+	return &Recorder{
+		dir:      cfg.Record.Dir,
+		rate:     cfg.Audio.SampleRate,
+		srcRate:  cfg.Audio.SampleRate,
+		channels: cfg.Audio.Channels,
+		tap:      sampleTap{rate: float64(cfg.Audio.SampleRate)},
+		preroll:  newRingBuffer(cfg.Audio.Channels, size),
+	}
+}
+
+// Feed hands the recorder the latest window read from src. It returns an
+// error only when a write fails and the recording was stopped.
+func (r *Recorder) Feed(src AudioSource, frames [][]float64) error {
+	// a file has one rate, so only follow the source between recordings
+	if r.writer == nil && r.srcRate != r.rate {
+		r.rate = r.srcRate
+		r.tap.rate = float64(r.rate)
+	}
+	before := r.tap.dropped
+	fresh := r.tap.fresh(src, frames)
+	if r.writer != nil {
+		r.dropped += r.tap.dropped - before
+	}
+	if fresh == nil {
+		return nil
+	}
+	if len(fresh) != r.channels {
+		fresh = matchChannels(fresh, r.channels)
+	}
+	if r.writer != nil {
+		if err := r.writer.Write(fresh); err != nil {
+			r.err = err
+			return r.Stop()
+		}
+		return nil
+	}
+	r.preroll.Write(fresh)
+	return nil
+}
+
+// SetSampleRate tells the recorder the source now runs at rate. It takes
+// effect once no recording is in progress.
+func (r *Recorder) SetSampleRate(rate int) { r.srcRate = rate }
+
+func (r *Recorder) Start(path string) error {
+	if r.writer != nil {
+		return nil
+	}
+	if path == "" {
+		path = filepath.Join(r.dir, "aviz-"+time.Now().Format("20060102-150405")+".wav")
+	}
+	w, err := newWAVWriter(path, r.rate, r.channels)
+	if err != nil {
+		return fmt.Errorf("cannot record: %w", err)
+	}
+
+	written := r.preroll.Written()
+	size := int64(len(r.preroll.data[0]))
+	if written > size {
+		written = size
+	}
+	held := r.preroll.Latest(int(written))
+	if err := w.Write(held); err != nil {
+		w.Close()
+		return fmt.Errorf("cannot record: %w", err)
+	}
+	r.preroll = newRingBuffer(r.channels, int(size))
+
+	r.writer = w
+	r.path = path
+	r.dropped = 0
+	r.err = nil
+	return nil
+}
+
+func (r *Recorder) Stop() error {
+	if r.writer == nil {
+		return nil
+	}
+	err := r.writer.Close()
+	r.writer = nil
+	if r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+func (r *Recorder) Recording() bool { return r.writer != nil }
+
+func (r *Recorder) Path() string { return r.path }
+
+func (r *Recorder) Elapsed() time.Duration {
+	if r.writer == nil {
+		return 0
+	}
+	return time.Duration(float64(r.writer.frames) / float64(r.rate) * float64(time.Second))
+}
+
+// Dropped is how much audio the current recording is missing because
+// frames were drawn too slowly to pick up every sample.
+func (r *Recorder) Dropped() time.Duration {
+	return time.Duration(float64(r.dropped) / float64(r.rate) * float64(time.Second))
+}
+
+func matchChannels(frames [][]float64, channels int) [][]float64 {
+	if channels == 1 {
+		return [][]float64{mixDown(frames)}
+	}
+	result := make([][]float64, channels)
+	for c := range result {
+		result[c] = frames[c%len(frames)]
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package main
+
+import "sync"
+
+type ringBuffer struct {
+	mu      sync.Mutex
+	data    [][]float64
+	pos     int
+	written int64
+}
+
+func newRingBuffer(channels, size int) *ringBuffer {
+	return &ringBuffer{data: makeFrames(channels, size)}
+}
+
+func (rb *ringBuffer) Write(frames [][]float64) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if len(frames) == 0 {
+		return
+	}
+	size := len(rb.data[0])
+	n := len(frames[0])
+	start := 0
+	if n > size {
+		start = n - size
+	}
+	for i := start; i < n; i++ {
+		for c := range rb.data {
+			if c < len(frames) {
+				rb.data[c][rb.pos] = frames[c][i]
+			}
+		}
+		rb.pos = (rb.pos + 1) % size
+	}
+	rb.written += int64(n)
+}
+
+func (rb *ringBuffer) Latest(n int) [][]float64 {
+	frames, _ := rb.LatestAt(n)
+	return frames
+}
+
+func (rb *ringBuffer) LatestAt(n int) ([][]float64, int64) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	size := len(rb.data[0])
+	if n > size {
+		n = size
+	}
+	result := makeFrames(len(rb.data), n)
+	start := rb.pos - n
+	if start < 0 {
+		start += size
+	}
+	for c := range rb.data {
+		first := copy(result[c], rb.data[c][start:])
+		if first < n {
+			copy(result[c][first:], rb.data[c][:n-first])
+		}
+	}
+	return result, rb.written
+}
+
+func (rb *ringBuffer) Written() int64 {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	return rb.written
+}
+
+func (rb *ringBuffer) Reset() {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	for c := range rb.data {
+		clear(rb.data[c])
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import "math"
+
+var scaleNames = []string{"log", "linear", "mel", "bark", "erb", "octave", "third-octave", "sixth-octave"}
+
+// freqScale maps frequencies onto an axis where bands are spaced evenly.
+type freqScale struct {
+	to, from func(float64) float64
+}
+
+var freqScales = map[string]freqScale{
+	"log": {math.Log, math.Exp},
+	"linear": {
+		func(f float64) float64 { return f },
+		func(v float64) float64 { return v },
+	},
+	"mel": {
+		func(f float64) float64 { return 2595 * math.Log10(1+f/700) },
+		func(m float64) float64 { return 700 * (math.Pow(10, m/2595) - 1) },
+	},
+	// Traunmüller's approximation of the Bark scale
+	"bark": {
+		func(f float64) float64 { return 26.81*f/(1960+f) - 0.53 },
+		func(z float64) float64 { return 1960 * (z + 0.53) / (26.28 - z) },
+	},
+	// ERB-rate after Glasberg & Moore
+	"erb": {
+		func(f float64) float64 { return 21.4 * math.Log10(1+0.00437*f) },
+		func(e float64) float64 { return (math.Pow(10, e/21.4) - 1) / 0.00437 },
+	},
+}
+
+// octaveFraction is b in the 1/b octave scales.
+func octaveFraction(scale string) int {
+	switch scale {
+	case "octave":
+		return 1
+	case "third-octave":
+		return 3
+	case "sixth-octave":
+		return 6
+	}
+	return 0
+}
+
+// scaleEdges returns the band edges between lo and hi. Octave scales use
+// the base-10 ISO 266 center frequencies that fall in range, so their band
+// count is fixed; the others split the range into n bands.
+func scaleEdges(scale string, lo, hi float64, n int) []float64 {
+	if b := octaveFraction(scale); b > 0 {
+		return octaveEdges(b, lo, hi)
+	}
+	fs, ok := freqScales[scale]
+	if !ok {
+		fs = freqScales["log"]
+	}
+	a, z := fs.to(lo), fs.to(hi)
+	edges := make([]float64, n+1)
+	for i := range edges {
+		edges[i] = fs.from(a + (z-a)*float64(i)/float64(n))
+	}
+	edges[0], edges[n] = lo, hi
+	return edges
+}
+
+func octaveEdges(b int, lo, hi float64) []float64 {
+	g := math.Pow(10, 0.3)
+	center := func(x int) float64 {
+		if b%2 == 1 {
+			return 1000 * math.Pow(g, float64(x)/float64(b))
+		}
+		return 1000 * math.Pow(g, float64(2*x+1)/float64(2*b))
+	}
+	halfBand := math.Pow(g, 1/float64(2*b))
+
+	x := int(math.Floor(float64(b) * math.Log(lo/1000) / math.Log(g)))
+	for center(x) < lo {
+		x++
+	}
+	edges := []float64{center(x) / halfBand}
+	for ; center(x) <= hi; x++ {
+		edges = append(edges, center(x)*halfBand)
+	}
+	if len(edges) < 2 {
+		edges = append(edges, center(x)*halfBand)
+	}
+	return edges
+}
+
+// scaleCenters returns the middle of each band measured on the scale
+// itself, which for octave bands is the geometric mean of the edges.
+func scaleCenters(scale string, edges []float64) []float64 {
+	fs, ok := freqScales[scale]
+	if !ok || octaveFraction(scale) > 0 {
+		fs = freqScales["log"]
+	}
+	centers := make([]float64, len(edges)-1)
+	for i := range centers {
+		centers[i] = fs.from((fs.to(edges[i]) + fs.to(edges[i+1])) / 2)
+	}
+	return centers
+}
+
+// minFreqSpan is the narrowest range zooming allows, as a ratio hi/lo,
+// and lowestFreq the bottom of the widest. defaultMinFreq and
+// defaultMaxFreq are the range shown when none is configured.
+const (
+	minFreqSpan    = 1.5
+	lowestFreq     = 10.0
+	defaultMinFreq = 30.0
+	defaultMaxFreq = 18000.0
+)
+
+// freqRange clamps the configured range to something analysable below
+// nyquist, falling back to the full range if min is not below max.
+func freqRange(lo, hi, nyquist float64) (float64, float64) {
+	hi = math.Min(hi, nyquist)
+	lo = math.Max(lo, lowestFreq)
+	if lo*minFreqSpan > hi {
+		return defaultMinFreq, math.Min(defaultMaxFreq, nyquist)
+	}
+	return lo, hi
+}
+
+// zoomRange scales the range's width by factor and shifts it by pan
+// widths, both measured in octaves so the view moves evenly at any
+// frequency. The result stays between lowestFreq and nyquist.
+func zoomRange(lo, hi, factor, pan, nyquist float64) (float64, float64) {
+	a, z := math.Log2(lo), math.Log2(hi)
+	mid, half := (a+z)/2, (z-a)/2
+	half = math.Max(half*factor, math.Log2(minFreqSpan)/2)
+	mid += pan * 2 * half
+
+	bottom, top := math.Log2(lowestFreq), math.Log2(nyquist)
+	half = math.Min(half, (top-bottom)/2)
+	mid = math.Max(bottom+half, math.Min(mid, top-half))
+	return math.Exp2(mid - half), math.Exp2(mid + half)
+}
+
+func NextScale(name string) string {
+	for i, n := range scaleNames {
+		if n == name {
+			return scaleNames[(i+1)%len(scaleNames)]
+		}
+	}
+	return scaleNames[0]
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed scenes/*.yaml
+var builtinScenes embed.FS
+
+const sceneStepsPerBar = 16
+
+type Scene struct {
+	Name  string      `yaml:"name"`
+	Tempo float64     `yaml:"tempo"`
+	Seed  int64       `yaml:"seed"`
+	Parts []ScenePart `yaml:"parts"`
+}
+
+type ScenePart struct {
+	Bars    int         `yaml:"bars"`
+	Kick    string      `yaml:"kick"`
+	Snare   string      `yaml:"snare"`
+	Hat     string      `yaml:"hat"`
+	Noise   string      `yaml:"noise"`
+	Chords  []string    `yaml:"chords"`
+	Bass    bool        `yaml:"bass"`
+	Sweep   *SceneSweep `yaml:"sweep"`
+	Silence bool        `yaml:"silence"`
+
+	start  float64
+	length float64
+	chords [][]float64
+}
+
+type SceneSweep struct {
+	From float64 `yaml:"from"`
+	To   float64 `yaml:"to"`
+}
+
+func SceneNames() []string {
+	entries, _ := builtinScenes.ReadDir("scenes")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".yaml"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func LoadScene(name string) (*Scene, error) {
+	data, err := builtinScenes.ReadFile("scenes/" + name + ".yaml")
+	if err != nil {
+		path := name
+		if home, herr := os.UserHomeDir(); herr == nil && !strings.ContainsRune(name, os.PathSeparator) && filepath.Ext(name) == "" {
+			path = filepath.Join(home, ".config", "audiovis", "scenes", name+".yaml")
+		}
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unknown demo scene %q (built-in: %s)", name, strings.Join(SceneNames(), ", "))
+		}
+	}
+
+	scene := &Scene{Name: name, Tempo: 120, Seed: 1}
+	if err := yaml.Unmarshal(data, scene); err != nil {
+		return nil, fmt.Errorf("parsing scene %s: %w", name, err)
+	}
+	if err := scene.prepare(); err != nil {
+		return nil, fmt.Errorf("scene %s: %w", name, err)
+	}
+	return scene, nil
+}
+
+func (s *Scene) prepare() error {
+	if s.Tempo <= 0 {
+		return errors.New("tempo must be positive")
+	}
+	if len(s.Parts) == 0 {
+		return errors.New("scene has no parts")
+	}
+
+	t := 0.0
+	for i := range s.Parts {
+		p := &s.Parts[i]
+		if p.Bars <= 0 {
+			p.Bars = 1
+		}
+		p.start = t
+		p.length = float64(p.Bars) * s.barLength()
+		t += p.length
+
+		for _, name := range p.Chords {
+			notes, err := parseChord(name)
+			if err != nil {
+				return fmt.Errorf("part %d: %w", i+1, err)
+			}
+			p.chords = append(p.chords, notes)
+		}
+	}
+	return nil
+}
+
+func (s *Scene) barLength() float64 { return 60 / s.Tempo * 4 }
+
+func (s *Scene) stepLength() float64 { return s.barLength() / sceneStepsPerBar }
+
+func (s *Scene) Length() float64 {
+	last := s.Parts[len(s.Parts)-1]
+	return last.start + last.length
+}
+
+func (s *Scene) sample(i int64, rate float64) (left, right float64) {
+	t := math.Mod(float64(i)/rate, s.Length())
+	part := &s.Parts[0]
+	for j := range s.Parts {
+		if t >= s.Parts[j].start {
+			part = &s.Parts[j]
+		}
+	}
+	if part.Silence {
+		return 0, 0
+	}
+
+	lt := t - part.start
+	step := s.stepLength()
+	center := 0.0
+
+	if tau, accent, ok := lastHit(part.Kick, lt, step); ok {
+		phase := 2 * math.Pi * (45*tau + (150.0-45)/25*(1-math.Exp(-25*tau)))
+		center += 0.9 * accent * math.Exp(-8*tau) * math.Sin(phase)
+	}
+	if tau, accent, ok := lastHit(part.Snare, lt, step); ok {
+		body := math.Sin(2*math.Pi*180*tau) * math.Exp(-15*tau) * 0.4
+		rattle := hashNoise(s.Seed, i) * math.Exp(-20*tau) * 0.6
+		center += 0.5 * accent * (body + rattle)
+	}
+	if tau, accent, ok := lastHit(part.Noise, lt, step); ok {
+		center += 0.35 * accent * hashNoise(s.Seed+1, i) * math.Exp(-6*tau)
+	}
+	if part.Sweep != nil && part.Sweep.From > 0 && part.Sweep.To > 0 {
+		k := math.Log(part.Sweep.To / part.Sweep.From)
+		phase := 2 * math.Pi * part.Sweep.From * lt
+		if k != 0 {
+			phase = 2 * math.Pi * part.Sweep.From * part.length / k * (math.Exp(lt/part.length*k) - 1)
+		}
+		center += 0.3 * math.Sin(phase)
+	}
+
+	left, right = center, center
+
+	if tau, accent, ok := lastHit(part.Hat, lt, step); ok {
+		hat := (hashNoise(s.Seed+2, i) - hashNoise(s.Seed+2, i-1)) * 0.5
+		v := 0.25 * accent * hat * math.Exp(-60*tau)
+		left += v * 0.7
+		right += v * 1.3
+	}
+
+	if len(part.chords) > 0 {
+		bar := int(lt / s.barLength())
+		barT := lt - float64(bar)*s.barLength()
+		notes := part.chords[bar%len(part.chords)]
+		env := math.Min(barT/0.01, 1) * (0.6 + 0.4*math.Exp(-barT*2))
+		for n, freq := range notes {
+			v := 0.15 * env * math.Sin(2*math.Pi*freq*barT)
+			if n%2 == 0 {
+				left += v * 1.3
+				right += v * 0.7
+			} else {
+				left += v * 0.7
+				right += v * 1.3
+			}
+		}
+		if part.Bass {
+			v := 0.35 * env * math.Sin(2*math.Pi*notes[0]/2*barT)
+			left += v
+			right += v
+		}
+	}
+
+	return left * 0.5, right * 0.5
+}
+
+func lastHit(pattern string, t, step float64) (tau, accent float64, ok bool) {
+	if pattern == "" {
+		return 0, 0, false
+	}
+	n := len(pattern)
+	cur := int(t / step)
+	for back := 0; back < n && back <= cur; back++ {
+		s := cur - back
+		switch pattern[s%n] {
+		case 'x':
+			return t - float64(s)*step, 0.7, true
+		case 'X':
+			return t - float64(s)*step, 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+var chordQualities = map[string][]int{
+	"":     {0, 4, 7},
+	"m":    {0, 3, 7},
+	"7":    {0, 4, 7, 10},
+	"m7":   {0, 3, 7, 10},
+	"maj7": {0, 4, 7, 11},
+	"dim":  {0, 3, 6},
+	"sus2": {0, 2, 7},
+	"sus4": {0, 5, 7},
+}
+
+func parseChord(name string) ([]float64, error) {
+	offsets := map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+	if name == "" {
+		return nil, errors.New("empty chord")
+	}
+	root, ok := offsets[name[0]]
+	if !ok {
+		return nil, fmt.Errorf("bad chord %q", name)
+	}
+	rest := name[1:]
+	if strings.HasPrefix(rest, "#") {
+		root++
+		rest = rest[1:]
+	} else if strings.HasPrefix(rest, "b") {
+		root--
+		rest = rest[1:]
+	}
+	intervals, ok := chordQualities[rest]
+	if !ok {
+		return nil, fmt.Errorf("bad chord %q", name)
+	}
+
+	notes := make([]float64, len(intervals))
+	for i, iv := range intervals {
+		notes[i] = midiToFreq(float64(48 + root + iv))
+	}
+	return notes, nil
+}
+
+type SceneAudio struct {
+	*synthSource
+	scene *Scene
+}
+
+func NewSceneAudio(scene *Scene, sampleRate, bufferSize, channels int) *SceneAudio {
+	sa := &SceneAudio{scene: scene}
+	rate := float64(sampleRate)
+	sa.synthSource = newSynthSource(sampleRate, bufferSize, channels, func(frames [][]float64, offset int64) {
+		for i := range frames[0] {
+			left, right := scene.sample(offset+int64(i), rate)
+			for c := range frames {
+				switch {
+				case len(frames) == 1:
+					frames[c][i] = (left + right) / 2
+				case c%2 == 0:
+					frames[c][i] = left
+				default:
+					frames[c][i] = right
+				}
+			}
+		}
+	})
+	return sa
+}
+
+func (sa *SceneAudio) Scene() string { return sa.scene.Name }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// runSend implements `aviz send`: capture audio locally and stream it to an
+// aviz started with --listen on another machine.
+func runSend(args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	configFile := fs.String("config", "", "Path to config file")
+	format := fs.String("format", "s16le", "Sample format on the wire: u8, s16le, s24le, s32le, f32le, f64le")
+	channels := fs.Int("channels", 0, "Channel count (default: the source's, else 2)")
+	rate := fs.Int("rate", 0, "Sample rate in Hz (default: the source's native rate, else 44100)")
+	backend := fs.String("backend", "", "Capture backend: auto, parec, pw-record, arecord, sox")
+	device := fs.String("device", "", "Capture device/source (default: monitor of the default sink)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: aviz send [flags] [udp://|tcp://]host[:port]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg := DefaultConfig()
+	cfg.TryLoadDefault()
+	if *configFile != "" {
+		if err := cfg.LoadFromFile(*configFile); err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+	}
+	if *channels > 0 {
+		cfg.Audio.Channels = *channels
+	}
+	if *rate > 0 {
+		cfg.Audio.SampleRate = *rate
+	}
+	if *backend != "" {
+		cfg.Audio.Backend = *backend
+	}
+	if *device != "" {
+		cfg.Audio.Device = *device
+	}
+	cfg.applyAudioDefaults()
+
+	logf := func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, "aviz send: "+format+"\n", args...)
+	}
+	sender, err := newNetSender(fs.Arg(0), *format, cfg.Audio.SampleRate, logf)
+	if err != nil {
+		return err
+	}
+	defer sender.Close()
+
+	capture, err := OpenCapture(cfg)
+	if err != nil {
+		return err
+	}
+	defer capture.Close()
+	logf("capturing %s via %s, %dHz %dch %s", capture.Device(), capture.Backend(), cfg.Audio.SampleRate, cfg.Audio.Channels, *format)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	var tap sampleTap
+	for {
+		select {
+		case <-sigCh:
+			return nil
+		case msg := <-capture.Notices():
+			logf("%s", msg)
+		case <-ticker.C:
+			sender.rate = capture.SampleRate()
+			before := tap.dropped
+			sender.Send(tap.fresh(capture, capture.Read()))
+			if tap.dropped > before {
+				logf("fell behind, dropped %d samples", tap.dropped-before)
+			}
+		}
+	}
+}
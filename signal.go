@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+var signalKinds = []string{"sine", "sweep", "white", "pink", "impulse", "multi"}
+
+const (
+	signalSweepLow    = 20.0
+	signalSweepHigh   = 20000.0
+	signalSweepLength = 10.0
+)
+
+type SignalGenerator struct {
+	*synthSource
+	mu     sync.Mutex
+	kind   string
+	freq   float64
+	level  float64
+	rate   float64
+	phases [3]float64
+	pink   [7]float64
+	last   int64
+}
+
+func NewSignalGenerator(kind string, freq, levelDB float64, sampleRate, bufferSize, channels int) (*SignalGenerator, error) {
+	valid := false
+	for _, k := range signalKinds {
+		if k == kind {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("unknown signal %q (want sine, sweep, white, pink, impulse, multi)", kind)
+	}
+
+	sg := &SignalGenerator{
+		kind:  kind,
+		freq:  freq,
+		level: levelDB,
+		rate:  float64(sampleRate),
+	}
+	sg.synthSource = newSynthSource(sampleRate, bufferSize, channels, sg.render)
+	return sg, nil
+}
+
+func (sg *SignalGenerator) render(frames [][]float64, offset int64) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	amp := math.Pow(10, sg.level/20)
+	for i := range frames[0] {
+		n := offset + int64(i)
+		v := 0.0
+		switch sg.kind {
+		case "sine":
+			v = sg.tone(0, sg.freq)
+		case "sweep":
+			v = sg.tone(0, sg.sweepFreq(n))
+		case "white":
+			v = hashNoise(0, n)
+		case "pink":
+			v = sg.pinkNoise(hashNoise(0, n))
+		case "impulse":
+			period := int64(math.Max(sg.rate/sg.freq, 1))
+			if n%period == 0 {
+				v = 1
+			}
+		case "multi":
+			v = (sg.tone(0, sg.freq) + sg.tone(1, sg.freq*4) + sg.tone(2, sg.freq*16)) / 3
+		}
+		for c := range frames {
+			frames[c][i] = v * amp
+		}
+		sg.last = n
+	}
+}
+
+func (sg *SignalGenerator) tone(idx int, freq float64) float64 {
+	sg.phases[idx] = math.Mod(sg.phases[idx]+2*math.Pi*freq/sg.rate, 2*math.Pi)
+	return math.Sin(sg.phases[idx])
+}
+
+func (sg *SignalGenerator) sweepFreq(n int64) float64 {
+	t := math.Mod(float64(n)/sg.rate, signalSweepLength) / signalSweepLength
+	return signalSweepLow * math.Pow(signalSweepHigh/signalSweepLow, t)
+}
+
+// pinkNoise filters white noise with Paul Kellet's refined -3dB/octave filter.
+func (sg *SignalGenerator) pinkNoise(white float64) float64 {
+	b := &sg.pink
+	b[0] = 0.99886*b[0] + white*0.0555179
+	b[1] = 0.99332*b[1] + white*0.0750759
+	b[2] = 0.96900*b[2] + white*0.1538520
+	b[3] = 0.86650*b[3] + white*0.3104856
+	b[4] = 0.55000*b[4] + white*0.5329522
+	b[5] = -0.7616*b[5] - white*0.0168980
+	v := b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + white*0.5362
+	b[6] = white * 0.115926
+	return v * 0.11
+}
+
+func (sg *SignalGenerator) AdjustFreq(semitones float64) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	sg.freq = clamp(sg.freq*math.Pow(2, semitones/12), signalSweepLow, signalSweepHigh)
+}
+
+func (sg *SignalGenerator) AdjustLevel(db float64) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	sg.level = clamp(sg.level+db, -60, 0)
+}
+
+func (sg *SignalGenerator) Describe() string {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	switch sg.kind {
+	case "sine", "impulse", "multi":
+		return fmt.Sprintf("%s %s %.0fdB", sg.kind, formatFreq(sg.freq), sg.level)
+	default:
+		return fmt.Sprintf("%s %.0fdB", sg.kind, sg.level)
+	}
+}
+
+// ExpectedFreqs are the frequencies that should produce the loudest band,
+// or nil for a broadband signal. The tones of multi are equally loud, so
+// any of the three may come out on top.
+func (sg *SignalGenerator) ExpectedFreqs() []float64 {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	switch sg.kind {
+	case "sine":
+		return []float64{sg.freq}
+	case "multi":
+		return []float64{sg.freq, sg.freq * 4, sg.freq * 16}
+	case "sweep":
+		return []float64{sg.sweepFreq(sg.last)}
+	default:
+		return nil
+	}
+}
+
+func drawSignalOverlay(screen tcell.Screen, w, h int, sg *SignalGenerator, edges, spectrum []float64) {
+	numBands := len(spectrum)
+	if numBands == 0 || len(edges) != numBands+1 {
+		return
+	}
+
+	measured := 0
+	for i, v := range spectrum {
+		if v > spectrum[measured] {
+			measured = i
+		}
+	}
+	mlo, mhi := edges[measured], edges[measured+1]
+
+	items := []string{
+		"signal    " + sg.Describe(),
+		"",
+	}
+	freqs := sg.ExpectedFreqs()
+	var inRange []float64
+	for _, f := range freqs {
+		if f >= edges[0] && f <= edges[numBands] {
+			inRange = append(inRange, f)
+		}
+	}
+	measuredLine := fmt.Sprintf("measured  band %3d  %s–%s", measured, formatFreq(mlo), formatFreq(mhi))
+	switch {
+	case freqs == nil:
+		items = append(items, "expected  broadband", measuredLine)
+	case inRange == nil:
+		items = append(items,
+			fmt.Sprintf("expected  %s, outside %s–%s", formatFreq(freqs[0]), formatFreq(edges[0]), formatFreq(edges[numBands])),
+			measuredLine,
+		)
+	default:
+		// the verdict is against the nearest expected band, so with
+		// several tones any of them peaking counts as a match
+		off := 0
+		for i, f := range inRange {
+			expected := bandForFreq(edges, f)
+			label := "expected "
+			if i > 0 {
+				label = "      or "
+			}
+			items = append(items, fmt.Sprintf("%s band %3d  %s–%s", label, expected, formatFreq(edges[expected]), formatFreq(edges[expected+1])))
+			if d := measured - expected; i == 0 || abs(d) < abs(off) {
+				off = d
+			}
+		}
+		verdict := "✓ match"
+		if off != 0 {
+			verdict = fmt.Sprintf("✗ off by %+d bands", off)
+		}
+		items = append(items, measuredLine, "", verdict)
+	}
+
+	drawListOverlay(screen, w, h, "SIGNAL GENERATOR", items, -1, "f/F freq  v/V level  g hide")
+}
+
+func formatFreq(f float64) string {
+	if f >= 1000 {
+		return fmt.Sprintf("%.1fkHz", f/1000)
+	}
+	return fmt.Sprintf("%.0fHz", f)
+}
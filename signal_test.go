@@ -0,0 +1,45 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMultiPeaksAtAnExpectedBand(t *testing.T) {
+	for _, mode := range []string{"relative", "absolute"} {
+		cfg := DefaultConfig()
+		cfg.Audio.SampleRate = 44100
+		cfg.DSP.Normalize = mode
+		sg, err := NewSignalGenerator("multi", 200, -12, 44100, cfg.Audio.BufferSize, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames := [][]float64{make([]float64, cfg.Audio.BufferSize)}
+		sg.render(frames, 0)
+
+		freqs := sg.ExpectedFreqs()
+		if len(freqs) != 3 {
+			t.Fatalf("multi expects %v, want its three tones", freqs)
+		}
+		spectra, edges := NewProcessor(cfg).Process(frames, 70)
+		var expected []int
+		for _, f := range freqs {
+			expected = append(expected, bandForFreq(edges, f))
+		}
+		if got := loudestBand(spectra[0]); !slices.Contains(expected, got) {
+			t.Errorf("%s: loudest band %d, want one of %v", mode, got, expected)
+		}
+	}
+}
+
+func TestExpectedFreqs(t *testing.T) {
+	for kind, want := range map[string]int{"sine": 1, "sweep": 1, "multi": 3, "white": 0, "pink": 0, "impulse": 0} {
+		sg, err := NewSignalGenerator(kind, 1000, -12, 44100, 4096, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(sg.ExpectedFreqs()); got != want {
+			t.Errorf("%s: %d expected frequencies, want %d", kind, got, want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func OpenAudioSource(cfg *Config) (AudioSource, error) {
+	switch {
+	case len(cfg.Audio.Sources) > 0:
+		return NewMixer(cfg)
+	case cfg.Signal != "":
+		return NewSignalGenerator(cfg.Signal, cfg.SignalFreq, cfg.SignalLevel, cfg.Audio.SampleRate, cfg.Audio.BufferSize, cfg.Audio.Channels)
+	case cfg.DemoMode && cfg.DemoScene != "":
+		scene, err := LoadScene(cfg.DemoScene)
+		if err != nil {
+			return nil, err
+		}
+		return NewSceneAudio(scene, cfg.Audio.SampleRate, cfg.Audio.BufferSize, cfg.Audio.Channels), nil
+	case cfg.DemoMode:
+		return NewDemoAudio(cfg.Audio.SampleRate, cfg.Audio.BufferSize, cfg.Audio.Channels), nil
+	case cfg.Audio.File != "":
+		rate := cfg.Audio.SampleRate
+		if cfg.Audio.AutoRate {
+			rate = 0
+		}
+		fs, err := NewFileSource(cfg.Audio.File, rate, cfg.Audio.BufferSize, cfg.Audio.Loop)
+		if err != nil {
+			return nil, fmt.Errorf("opening file: %w", err)
+		}
+		cfg.Audio.SampleRate = int(fs.outRate)
+		return fs, nil
+	case cfg.Audio.Input != "" || cfg.Audio.SourceCmd != "":
+		sf, err := ParseSampleFormat(cfg.Audio.Format)
+		if err != nil {
+			return nil, err
+		}
+		ps, err := NewPipeSource(cfg.Audio.Input, cfg.Audio.SourceCmd, sf, cfg.Audio.Channels, cfg.Audio.BufferSize, cfg.Audio.HopSize)
+		if err != nil {
+			return nil, fmt.Errorf("opening input: %w", err)
+		}
+		return ps, nil
+	case cfg.Audio.Listen != "":
+		jitter := time.Duration(cfg.Audio.JitterMS) * time.Millisecond
+		ns, err := NewNetSource(cfg.Audio.Listen, jitter, cfg.Audio.SampleRate, cfg.Audio.BufferSize, cfg.Audio.HopSize, cfg.Audio.Channels)
+		if err != nil {
+			return nil, fmt.Errorf("listening: %w", err)
+		}
+		return ns, nil
+	case cfg.Audio.URL != "":
+		ss, err := NewStreamSource(cfg.Audio.URL, cfg.Audio.SampleRate, cfg.Audio.BufferSize, cfg.Audio.HopSize)
+		if err != nil {
+			return nil, fmt.Errorf("opening stream: %w", err)
+		}
+		return ss, nil
+	default:
+		return OpenCapture(cfg)
+	}
+}
+
+func capturesLive(cfg *Config) bool {
+	return len(cfg.Audio.Sources) == 0 && cfg.Signal == "" && !cfg.DemoMode &&
+		cfg.Audio.File == "" && cfg.Audio.Input == "" && cfg.Audio.SourceCmd == "" &&
+		cfg.Audio.Listen == "" && cfg.Audio.URL == ""
+}
+
+func (sc SourceConfig) config(base *Config) (*Config, error) {
+	c := *base
+	c.Audio.Sources = nil
+	c.DemoMode = false
+	c.DemoScene = ""
+	c.Signal = ""
+	c.Audio.File = ""
+	c.Audio.Input = ""
+	c.Audio.SourceCmd = ""
+	c.Audio.Listen = ""
+	c.Audio.URL = ""
+	c.Audio.App = ""
+	c.Audio.SinkInput = ""
+	// mixed sources are summed sample for sample, so they all run at the
+	// parent's rate rather than each probing its own
+	c.Audio.AutoRate = false
+
+	switch sc.Type {
+	case "", "capture":
+		c.Audio.Device = sc.Device
+		c.Audio.App = sc.App
+		if sc.Backend != "" {
+			c.Audio.Backend = sc.Backend
+		}
+	case "file":
+		c.Audio.File = sc.Path
+		c.Audio.Loop = sc.Loop
+	case "pipe":
+		c.Audio.Input = sc.Path
+		c.Audio.SourceCmd = sc.Command
+		if sc.Format != "" {
+			c.Audio.Format = sc.Format
+		}
+	case "net":
+		c.Audio.Listen = sc.Listen
+	case "stream":
+		c.Audio.URL = sc.URL
+	case "demo":
+		c.DemoMode = true
+		c.DemoScene = sc.Scene
+	case "signal":
+		c.Signal = sc.Signal
+		c.SignalFreq = sc.Freq
+		if c.SignalFreq <= 0 {
+			c.SignalFreq = 1000
+		}
+		c.SignalLevel = -12
+		if sc.Level != nil {
+			c.SignalLevel = *sc.Level
+		}
+	default:
+		return nil, fmt.Errorf("unknown source type %q (want capture, file, pipe, net, stream, demo, signal)", sc.Type)
+	}
+	return &c, nil
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// streamLead is how far decoding may run ahead of the wall clock. Servers
+// that send a burst on connect (or a plain file) are throttled to real time.
+const streamLead = 500 * time.Millisecond
+
+type StreamSource struct {
+	stream  *pcmStream
+	url     string
+	host    string
+	rate    int
+	client  *http.Client
+	mu      sync.Mutex
+	body    io.Closer
+	title   string
+	closed  bool
+	done    chan struct{}
+	notices chan string
+}
+
+func NewStreamSource(rawURL string, sampleRate, bufferSize, hopSize int) (*StreamSource, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported stream url %q (want http or https)", rawURL)
+	}
+	ss := &StreamSource{
+		stream:  newPCMStream(mustSampleFormat("s16le"), 2, bufferSize, hopSize),
+		url:     rawURL,
+		host:    u.Host,
+		rate:    sampleRate,
+		client:  &http.Client{},
+		done:    make(chan struct{}),
+		notices: make(chan string, 4),
+	}
+
+	// fail fast on a bad url, later drops are retried in the background
+	body, err := ss.connect()
+	if err != nil {
+		return nil, err
+	}
+	go ss.run(body)
+	return ss, nil
+}
+
+func (ss *StreamSource) connect() (io.Reader, error) {
+	req, err := http.NewRequest("GET", ss.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Icy-MetaData", "1")
+	resp, err := ss.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", ss.host, resp.Status)
+	}
+	if !ss.setBody(resp.Body) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("stream closed")
+	}
+
+	var r io.Reader = resp.Body
+	if metaint, err := strconv.Atoi(resp.Header.Get("Icy-Metaint")); err == nil && metaint > 0 {
+		r = &icyReader{r: resp.Body, metaint: metaint, remaining: metaint, onTitle: ss.setTitle}
+	}
+	if name := resp.Header.Get("Icy-Name"); name != "" {
+		ss.notify("Tuned in: " + name)
+	}
+	return r, nil
+}
+
+func (ss *StreamSource) run(body io.Reader) {
+	backoff := captureMinBackoff
+	for {
+		if body != nil {
+			started := time.Now()
+			ss.play(body)
+			ss.stream.silence()
+			if time.Since(started) > captureMaxBackoff {
+				backoff = captureMinBackoff
+			}
+		}
+		if ss.isClosed() {
+			return
+		}
+
+		select {
+		case <-ss.done:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, captureMaxBackoff)
+
+		var err error
+		if body, err = ss.connect(); err != nil {
+			ss.notify("Stream unavailable: " + err.Error())
+		} else {
+			ss.notify("Stream reconnected")
+		}
+	}
+}
+
+func (ss *StreamSource) play(body io.Reader) {
+	dec, err := mp3.NewDecoder(body)
+	if err != nil {
+		if !ss.isClosed() {
+			ss.notify("Cannot decode stream: " + err.Error())
+		}
+		return
+	}
+
+	ss.mu.Lock()
+	ss.rate = dec.SampleRate()
+	ss.mu.Unlock()
+
+	_ = ss.stream.readFrom(&pacedReader{r: dec, bytesPerSec: float64(dec.SampleRate() * 4), start: time.Now()})
+	if !ss.isClosed() {
+		ss.notify("Stream dropped, reconnecting")
+	}
+}
+
+func (ss *StreamSource) setBody(body io.Closer) bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.closed {
+		return false
+	}
+	// a body still set here belongs to a connection we gave up on
+	if ss.body != nil {
+		ss.body.Close()
+	}
+	ss.body = body
+	return true
+}
+
+func (ss *StreamSource) isClosed() bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.closed
+}
+
+func (ss *StreamSource) setTitle(title string) {
+	ss.mu.Lock()
+	changed := title != ss.title
+	ss.title = title
+	ss.mu.Unlock()
+	if changed && title != "" {
+		ss.notify("Now playing: " + title)
+	}
+}
+
+// Title is the current ICY StreamTitle, or the host when the server sends
+// no metadata.
+func (ss *StreamSource) Title() string {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.title != "" {
+		return ss.title
+	}
+	return ss.host
+}
+
+func (ss *StreamSource) notify(msg string) {
+	select {
+	case ss.notices <- msg:
+	default:
+	}
+}
+
+func (ss *StreamSource) Notices() <-chan string { return ss.notices }
+
+// SampleRate is the rate of the MP3 being decoded, or the configured rate
+// before the first frame.
+func (ss *StreamSource) SampleRate() int {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.rate
+}
+
+func (ss *StreamSource) Read() [][]float64 {
+	return ss.stream.Read()
+}
+
+func (ss *StreamSource) SampleCount() int64 {
+	return ss.stream.SampleCount()
+}
+
+func (ss *StreamSource) Close() {
+	ss.mu.Lock()
+	if ss.closed {
+		ss.mu.Unlock()
+		return
+	}
+	ss.closed = true
+	close(ss.done)
+	body := ss.body
+	ss.mu.Unlock()
+	if body != nil {
+		body.Close()
+	}
+}
+
+// icyReader strips the metadata blocks a SHOUTcast/Icecast server inserts
+// every metaint bytes of audio when asked with Icy-MetaData: 1.
+type icyReader struct {
+	r         io.Reader
+	metaint   int
+	remaining int
+	onTitle   func(string)
+}
+
+func (ir *icyReader) Read(p []byte) (int, error) {
+	if ir.remaining == 0 {
+		if err := ir.readMeta(); err != nil {
+			return 0, err
+		}
+		ir.remaining = ir.metaint
+	}
+	if len(p) > ir.remaining {
+		p = p[:ir.remaining]
+	}
+	n, err := ir.r.Read(p)
+	ir.remaining -= n
+	return n, err
+}
+
+func (ir *icyReader) readMeta() error {
+	var length [1]byte
+	if _, err := io.ReadFull(ir.r, length[:]); err != nil {
+		return err
+	}
+	if length[0] == 0 {
+		return nil
+	}
+	meta := make([]byte, int(length[0])*16)
+	if _, err := io.ReadFull(ir.r, meta); err != nil {
+		return err
+	}
+	if title, ok := parseStreamTitle(string(bytes.TrimRight(meta, "\x00"))); ok {
+		ir.onTitle(title)
+	}
+	return nil
+}
+
+// parseStreamTitle pulls the title out of metadata such as
+// StreamTitle='Artist - Song';
+func parseStreamTitle(meta string) (string, bool) {
+	const key = "StreamTitle='"
+	i := strings.Index(meta, key)
+	if i < 0 {
+		return "", false
+	}
+	rest := meta[i+len(key):]
+	end := strings.Index(rest, "';")
+	if end < 0 {
+		end = strings.LastIndex(rest, "'")
+	}
+	if end < 0 {
+		return "", false
+	}
+	return strings.TrimSpace(rest[:end]), true
+}
+
+// pacedReader blocks so that no more than bytesPerSec is read on average,
+// allowing streamLead of read-ahead.
+type pacedReader struct {
+	r           io.Reader
+	bytesPerSec float64
+	start       time.Time
+	read        int64
+}
+
+func (pr *pacedReader) Read(p []byte) (int, error) {
+	ahead := time.Duration(float64(pr.read)/pr.bytesPerSec*float64(time.Second)) - time.Since(pr.start)
+	if ahead > streamLead {
+		time.Sleep(ahead - streamLead)
+	}
+	n, err := pr.r.Read(p)
+	pr.read += int64(n)
+	return n, err
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// silentMP3 returns frames of MPEG-1 layer III, 128kbps 48kHz stereo, with
+// all-zero side info so every granule decodes to silence.
+func silentMP3(frames int) []byte {
+	frame := make([]byte, 144*128000/48000)
+	copy(frame, []byte{0xff, 0xfb, 0x94, 0x00})
+	return bytes.Repeat(frame, frames)
+}
+
+// icyBlock encodes a metadata block as a server sends it after every
+// metaint bytes of audio.
+func icyBlock(meta string) []byte {
+	n := (len(meta) + 15) / 16
+	block := make([]byte, 1+n*16)
+	block[0] = byte(n)
+	copy(block[1:], meta)
+	return block
+}
+
+// withICY interleaves audio with a metadata block every metaint bytes,
+// taking the blocks' text from metas in turn ("" for an empty block).
+func withICY(audio []byte, metaint int, metas ...string) []byte {
+	var out bytes.Buffer
+	for i := 0; len(audio) > 0; i++ {
+		n := min(metaint, len(audio))
+		out.Write(audio[:n])
+		audio = audio[n:]
+		if n < metaint {
+			break
+		}
+		meta := ""
+		if i < len(metas) {
+			meta = metas[i]
+		}
+		out.Write(icyBlock(meta))
+	}
+	return out.Bytes()
+}
+
+func TestParseStreamTitle(t *testing.T) {
+	tests := []struct {
+		meta  string
+		title string
+		ok    bool
+	}{
+		{"StreamTitle='Artist - Song';", "Artist - Song", true},
+		{"StreamTitle='Artist - Song';StreamUrl='http://example.com';", "Artist - Song", true},
+		{"StreamTitle='Guns N' Roses - Don't Cry';StreamUrl='';", "Guns N' Roses - Don't Cry", true},
+		{"StreamTitle='It's Over'", "It's Over", true},
+		{"StreamTitle='';", "", true},
+		{"StreamUrl='http://example.com';", "", false},
+		{"StreamTitle=", "", false},
+	}
+	for _, tt := range tests {
+		title, ok := parseStreamTitle(tt.meta)
+		if title != tt.title || ok != tt.ok {
+			t.Errorf("parseStreamTitle(%q) = %q, %v; want %q, %v", tt.meta, title, ok, tt.title, tt.ok)
+		}
+	}
+}
+
+func TestIcyReader(t *testing.T) {
+	audio := make([]byte, 100)
+	for i := range audio {
+		audio[i] = byte(i)
+	}
+	data := withICY(audio, 16, "StreamTitle='One';", "", "StreamTitle='Don't Stop';")
+
+	var titles []string
+	r := &icyReader{r: bytes.NewReader(data), metaint: 16, remaining: 16, onTitle: func(s string) { titles = append(titles, s) }}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, audio) {
+		t.Errorf("audio after stripping metadata differs:\n got %v\nwant %v", got, audio)
+	}
+	if want := []string{"One", "Don't Stop"}; fmt.Sprint(titles) != fmt.Sprint(want) {
+		t.Errorf("titles %q, want %q", titles, want)
+	}
+}
+
+func TestStreamSource(t *testing.T) {
+	const metaint = 1024
+	const title = "Guns N' Roses - Sweet Child O' Mine"
+	body := withICY(silentMP3(40), metaint, "StreamTitle='"+title+"';")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Icy-MetaData") != "1" {
+			t.Errorf("request without Icy-MetaData: 1")
+		}
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Header().Set("Icy-Metaint", fmt.Sprint(metaint))
+		w.Header().Set("Icy-Name", "Test Radio")
+		w.Write(body)
+		w.(http.Flusher).Flush()
+		// hold the connection open like a live stream
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ss, err := NewStreamSource(server.URL+"/live.mp3", 44100, 4096, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ss.Close()
+
+	var notices []string
+	deadline := time.After(5 * time.Second)
+	for {
+		// the sample count only moves when the stream is read
+		ss.Read()
+		if ss.Title() == title && ss.SampleRate() == 48000 && ss.SampleCount() > 0 {
+			break
+		}
+		select {
+		case msg := <-ss.Notices():
+			notices = append(notices, msg)
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("title %q, rate %d, %d samples; notices %q", ss.Title(), ss.SampleRate(), ss.SampleCount(), notices)
+		}
+	}
+	for len(ss.Notices()) > 0 {
+		notices = append(notices, <-ss.Notices())
+	}
+	if !strings.Contains(strings.Join(notices, "\n"), "Tuned in: Test Radio") {
+		t.Errorf("notices %q, want the Icy-Name", notices)
+	}
+
+	frames := ss.Read()
+	if len(frames) != 2 || len(frames[0]) != 4096 {
+		t.Fatalf("Read returned %dx%d frames, want 2x4096", len(frames), len(frames[0]))
+	}
+	for _, ch := range frames {
+		for _, v := range ch {
+			if v != 0 {
+				t.Fatalf("silent stream decoded to %v", v)
+			}
+		}
+	}
+}
+
+func TestStreamSourceBadURL(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if _, err := NewStreamSource(server.URL, 44100, 4096, 512); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("got %v, want a 404 error", err)
+	}
+	if _, err := NewStreamSource("ftp://example.com/radio", 44100, 4096, 512); err == nil {
+		t.Errorf("ftp url accepted")
+	}
+}
+
+func TestStreamSourceReconnectClosesBody(t *testing.T) {
+	var mu sync.Mutex
+	requests, open := 0, 0
+	stop := make(chan struct{})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		// a layer I header makes the decoder give up at once while the
+		// connection stays open, so each retry must close the last body
+		w.Write(bytes.Repeat([]byte{0xff, 0xfe, 0x94, 0x00}, 1000))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-stop:
+		}
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch state {
+		case http.StateNew:
+			open++
+		case http.StateClosed, http.StateHijacked:
+			open--
+		}
+	}
+	server.Start()
+	defer server.Close()
+	defer close(stop)
+
+	ss, err := NewStreamSource(server.URL, 44100, 4096, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ss.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		r, o := requests, open
+		mu.Unlock()
+		if r >= 3 && o <= 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d connections still open after %d requests", o, r)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+type synthSource struct {
+	rate       float64
+	bufferSize int
+	ring       *ringBuffer
+	start      time.Time
+	generated  int64
+	render     func(frames [][]float64, offset int64)
+}
+
+func newSynthSource(sampleRate, bufferSize, channels int, render func(frames [][]float64, offset int64)) *synthSource {
+	return &synthSource{
+		rate:       float64(sampleRate),
+		bufferSize: bufferSize,
+		ring:       newRingBuffer(channels, bufferSize),
+		start:      time.Now(),
+		render:     render,
+	}
+}
+
+func (ss *synthSource) Read() [][]float64 {
+	target := int64(time.Since(ss.start).Seconds() * ss.rate)
+	if target-ss.generated > int64(ss.bufferSize) {
+		ss.generated = target - int64(ss.bufferSize)
+	}
+	if missing := int(target - ss.generated); missing > 0 {
+		frames := makeFrames(len(ss.ring.data), missing)
+		ss.render(frames, ss.generated)
+		ss.ring.Write(frames)
+		ss.generated = target
+	}
+	return ss.ring.Latest(ss.bufferSize)
+}
+
+func (ss *synthSource) SampleCount() int64 { return ss.generated }
+
+func (ss *synthSource) Close() {}
+
+// hashNoise returns deterministic white noise in [-1, 1) for a sample index.
+func hashNoise(seed, i int64) float64 {
+	z := uint64(seed)*0x9E3779B97F4A7C15 + uint64(i)
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	z ^= z >> 31
+	return float64(z>>11)/float64(1<<52) - 1
+}
+
+func midiToFreq(note float64) float64 {
+	return 440 * math.Pow(2, (note-69)/12)
+}
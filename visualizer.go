@@ -7,6 +7,10 @@ type Visualizer interface {
 	Draw(screen tcell.Screen, spectrum []float64, rawSamples []float64, w, h int, scheme ColorScheme, cfg *Config)
 }
 
+type StereoVisualizer interface {
+	DrawStereo(screen tcell.Screen, spectra [][]float64, rawSamples [][]float64, w, h int, scheme ColorScheme, cfg *Config)
+}
+
 var visualizerNames = []string{"bars", "wave", "spectrum", "circle", "fire"}
 
 func GetVisualizer(name string) Visualizer {
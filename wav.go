@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+type wavInfo struct {
+	format     uint16
+	channels   int
+	sampleRate int
+	bits       int
+	sample     SampleFormat
+}
+
+func LoadWAV(path string) ([][]float32, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	return decodeWAV(f)
+}
+
+func decodeWAV(r io.Reader) ([][]float32, int, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, 0, fmt.Errorf("reading wav header: %w", err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, 0, errors.New("not a RIFF/WAVE file")
+	}
+
+	var info *wavInfo
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return nil, 0, errors.New("wav has no data chunk")
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			body := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, 0, fmt.Errorf("reading fmt chunk: %w", err)
+			}
+			parsed, err := parseWAVFormat(body[:size])
+			if err != nil {
+				return nil, 0, err
+			}
+			info = parsed
+		case "data":
+			if info == nil {
+				return nil, 0, errors.New("wav data chunk before fmt chunk")
+			}
+			return readWAVData(io.LimitReader(r, size), info)
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return nil, 0, fmt.Errorf("skipping %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+func parseWAVFormat(body []byte) (*wavInfo, error) {
+	if len(body) < 16 {
+		return nil, errors.New("wav fmt chunk too short")
+	}
+	info := &wavInfo{
+		format:     binary.LittleEndian.Uint16(body[0:2]),
+		channels:   int(binary.LittleEndian.Uint16(body[2:4])),
+		sampleRate: int(binary.LittleEndian.Uint32(body[4:8])),
+		bits:       int(binary.LittleEndian.Uint16(body[14:16])),
+	}
+	if info.format == wavFormatExtensible {
+		if len(body) < 26 {
+			return nil, errors.New("wav extensible fmt chunk too short")
+		}
+		info.format = binary.LittleEndian.Uint16(body[24:26])
+	}
+	if info.channels < 1 || info.sampleRate < 1 {
+		return nil, errors.New("wav has no channels or sample rate")
+	}
+
+	var name string
+	switch {
+	case info.format == wavFormatPCM && info.bits == 8:
+		name = "u8"
+	case info.format == wavFormatPCM && (info.bits == 16 || info.bits == 24 || info.bits == 32):
+		name = fmt.Sprintf("s%dle", info.bits)
+	case info.format == wavFormatFloat && (info.bits == 32 || info.bits == 64):
+		name = fmt.Sprintf("f%dle", info.bits)
+	default:
+		return nil, fmt.Errorf("unsupported wav encoding (format %d, %d bits)", info.format, info.bits)
+	}
+	info.sample = mustSampleFormat(name)
+	return info, nil
+}
+
+func readWAVData(r io.Reader, info *wavInfo) ([][]float32, int, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading wav data: %w", err)
+	}
+
+	sampleSize := info.sample.Size
+	frameSize := sampleSize * info.channels
+	numFrames := len(raw) / frameSize
+	data := make([][]float32, info.channels)
+	for c := range data {
+		data[c] = make([]float32, numFrames)
+	}
+	for i := 0; i < numFrames; i++ {
+		for c := 0; c < info.channels; c++ {
+			off := i*frameSize + c*sampleSize
+			data[c][i] = float32(info.sample.Decode(raw[off : off+sampleSize]))
+		}
+	}
+	return data, info.sampleRate, nil
+}
+
+type wavWriter struct {
+	f        *os.File
+	w        *bufio.Writer
+	channels int
+	frames   int64
+}
+
+func newWAVWriter(path string, sampleRate, channels int) (*wavWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	ww := &wavWriter{f: f, w: bufio.NewWriter(f), channels: channels}
+
+	var h [44]byte
+	copy(h[0:4], "RIFF")
+	copy(h[8:16], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:20], 16)
+	binary.LittleEndian.PutUint16(h[20:22], wavFormatFloat)
+	binary.LittleEndian.PutUint16(h[22:24], uint16(channels))
+	binary.LittleEndian.PutUint32(h[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(h[28:32], uint32(sampleRate*channels*4))
+	binary.LittleEndian.PutUint16(h[32:34], uint16(channels*4))
+	binary.LittleEndian.PutUint16(h[34:36], 32)
+	copy(h[36:40], "data")
+	if _, err := ww.w.Write(h[:]); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return ww, nil
+}
+
+func (ww *wavWriter) Write(frames [][]float64) error {
+	if len(frames) == 0 {
+		return nil
+	}
+	var b [4]byte
+	for i := range frames[0] {
+		for c := 0; c < ww.channels; c++ {
+			v := frames[c%len(frames)][i]
+			binary.LittleEndian.PutUint32(b[:], math.Float32bits(float32(v)))
+			if _, err := ww.w.Write(b[:]); err != nil {
+				return err
+			}
+		}
+	}
+	ww.frames += int64(len(frames[0]))
+	return nil
+}
+
+func (ww *wavWriter) Close() error {
+	if err := ww.w.Flush(); err != nil {
+		ww.f.Close()
+		return err
+	}
+	dataSize := uint32(ww.frames * int64(ww.channels) * 4)
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], 36+dataSize)
+	if _, err := ww.f.WriteAt(b[:], 4); err != nil {
+		ww.f.Close()
+		return err
+	}
+	binary.LittleEndian.PutUint32(b[:], dataSize)
+	if _, err := ww.f.WriteAt(b[:], 40); err != nil {
+		ww.f.Close()
+		return err
+	}
+	return ww.f.Close()
+}
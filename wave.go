@@ -98,6 +98,65 @@ func (wv *WaveVisualizer) Draw(screen tcell.Screen, spectrum []float64, rawSampl
 	wv.phase += 0.02
 }
 
+func (wv *WaveVisualizer) DrawStereo(screen tcell.Screen, spectra [][]float64, rawSamples [][]float64, w, h int, scheme ColorScheme, cfg *Config) {
+	drawH := h - 1
+	if !cfg.Visual.ShowStatus {
+		drawH = h
+	}
+	if drawH < 2 {
+		return
+	}
+
+	canvas := NewBrailleCanvas(w, drawH)
+
+	pw := canvas.PixelWidth()
+	ph := canvas.PixelHeight()
+	halfH := ph / 2
+	leftY := halfH / 2
+	rightY := halfH + halfH/2
+	amp := float64(halfH/2) * cfg.Visual.Sensitivity * 3.0
+
+	left := resample(rawSamples[0], pw)
+	right := resample(rawSamples[1], pw)
+
+	for x := 0; x < pw-1; x++ {
+		t := float64(x) / float64(pw)
+
+		y0 := leftY - int(left[x]*amp)
+		y1 := leftY - int(left[x+1]*amp)
+		canvas.DrawLine(x, clampInt(y0, 0, halfH-1), x+1, clampInt(y1, 0, halfH-1), scheme.At(t))
+
+		y0 = rightY + int(right[x]*amp)
+		y1 = rightY + int(right[x+1]*amp)
+		canvas.DrawLine(x, clampInt(y0, halfH, ph-1), x+1, clampInt(y1, halfH, ph-1), scheme.At(1-t))
+	}
+
+	centerCellY := drawH / 2
+	dimStyle := tcell.StyleDefault.Foreground(scheme.At(0.5)).Dim(true)
+	for x := 0; x < w; x++ {
+		screen.SetContent(x, centerCellY, '·', nil, dimStyle)
+	}
+
+	leftBg := resample(spectra[0], w)
+	rightBg := resample(spectra[1], w)
+	for x := 0; x < w; x++ {
+		upH := int(leftBg[x] * 0.3 * float64(centerCellY))
+		for y := centerCellY - 1; y >= centerCellY-upH && y >= 0; y-- {
+			st := tcell.StyleDefault.Foreground(dimmedColor(scheme.At(float64(centerCellY-y)/float64(centerCellY)), 0.15))
+			screen.SetContent(x, y, '░', nil, st)
+		}
+		downH := int(rightBg[x] * 0.3 * float64(drawH-centerCellY))
+		for y := centerCellY + 1; y <= centerCellY+downH && y < drawH; y++ {
+			st := tcell.StyleDefault.Foreground(dimmedColor(scheme.At(float64(y-centerCellY)/float64(drawH-centerCellY)), 0.15))
+			screen.SetContent(x, y, '░', nil, st)
+		}
+	}
+
+	canvas.Render(screen, 0, 0)
+
+	wv.phase += 0.02
+}
+
 func clampInt(v, min, max int) int {
 	if v < min {
 		return min
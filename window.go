@@ -0,0 +1,82 @@
+package main
+
+import "math"
+
+var windowNames = []string{"hann", "blackman-harris", "flat-top", "kaiser", "rectangular"}
+
+// cosineWindow builds a generalized cosine window
+// a0 - a1·cos(x) + a2·cos(2x) - ...
+func cosineWindow(n int, coeffs ...float64) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		x := 2 * math.Pi * float64(i) / float64(max(n-1, 1))
+		sign := 1.0
+		for k, a := range coeffs {
+			w[i] += sign * a * math.Cos(float64(k)*x)
+			sign = -sign
+		}
+	}
+	return w
+}
+
+func kaiserWindow(n int, beta float64) []float64 {
+	w := make([]float64, n)
+	denom := besselI0(beta)
+	for i := range w {
+		r := 2*float64(i)/float64(max(n-1, 1)) - 1
+		w[i] = besselI0(beta*math.Sqrt(math.Max(0, 1-r*r))) / denom
+	}
+	return w
+}
+
+// besselI0 is the zeroth order modified Bessel function of the first kind.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+		if term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+// makeWindow returns the named window of length n. Unknown names fall
+// back to Hann.
+func makeWindow(name string, n int, kaiserBeta float64) []float64 {
+	switch name {
+	case "rectangular":
+		return cosineWindow(n, 1)
+	case "blackman-harris":
+		return cosineWindow(n, 0.35875, 0.48829, 0.14128, 0.01168)
+	case "flat-top":
+		return cosineWindow(n, 0.21557895, 0.41663158, 0.277263158, 0.083578947, 0.006947368)
+	case "kaiser":
+		return kaiserWindow(n, kaiserBeta)
+	default:
+		return cosineWindow(n, 0.5, 0.5)
+	}
+}
+
+// coherentGain is the window's mean value, i.e. how much it attenuates a
+// sinusoid's peak compared to a rectangular window.
+func coherentGain(w []float64) float64 {
+	sum := 0.0
+	for _, v := range w {
+		sum += v
+	}
+	if sum <= 0 {
+		return 1
+	}
+	return sum / float64(len(w))
+}
+
+func NextWindow(name string) string {
+	for i, n := range windowNames {
+		if n == name {
+			return windowNames[(i+1)%len(windowNames)]
+		}
+	}
+	return windowNames[0]
+}